@@ -1,13 +1,24 @@
 package config
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
+	"reflect"
+	"strconv"
 	"strings"
 )
 
 type Config struct {
 	UserName    string `json:"user_name"`
-	AccessToken string `json:"access_token"`
+	AccessToken string `json:"access_token" secret:"true"`
+	APIURL      string `json:"api_url"`
+	GraphQLURL  string `json:"graphql_url"`
+
+	AppID             string `json:"app_id"`
+	AppInstallationID int64  `json:"app_installation_id"`
+	AppPrivateKey     string `json:"app_private_key" secret:"true"`
+	AppPrivateKeyPath string `json:"app_private_key_path"`
 
 	ExcludeRepos []string `json:"exclude_repos"`
 	ExcludeLangs []string `json:"exclude_langs"`
@@ -20,59 +31,299 @@ type Config struct {
 
 	IgnoreLinesChanged bool `json:"ignore_lines_changed"`
 	IgnoreRepoViews    bool `json:"ignore_repo_views"`
+	IgnorePinnedRepos  bool `json:"ignore_pinned_repos"`
+
+	ErrorPolicy string `json:"error_policy"`
+
+	// Window is one of the stats.WindowPresets, Since and Until are dates
+	// in the YYYY-MM-DD format for a custom window.
+	Window string `json:"window"`
+	Since  string `json:"since"`
+	Until  string `json:"until"`
+
+	// StreakTimezone is an IANA timezone name deciding when a day ends for
+	// the current streak.
+	StreakTimezone     string `json:"streak_timezone"`
+	StreakSkipWeekends bool   `json:"streak_skip_weekends"`
+
+	DisableHistory bool `json:"disable_history"`
+	DeltaDays      int  `json:"delta_days"`
 
-	Animation  bool   `json:"animation"`
-	WebhookURL string `json:"webhook_url"`
+	CacheDir               string `json:"cache_dir"`
+	GraphQLCacheTTLSeconds int    `json:"graphql_cache_ttl_seconds"`
+
+	Animation     bool            `json:"animation"`
+	CalendarTheme string          `json:"calendar_theme"`
+	TopReposBy    string          `json:"top_repos_by"`
+	TopReposCount int             `json:"top_repos_count"`
+	Webhooks      []WebhookTarget `json:"webhooks"`
+
+	// TemplateDir holds *.gohtml card templates, see render.LoadTemplates.
+	TemplateDir string `json:"template_dir"`
 }
 
-func NewConfig(tokenValidate func(token string) bool) *Config {
-	accessToken := os.Getenv("ACCESS_TOKEN")
-	if !tokenValidate(accessToken) {
-		accessToken = os.Getenv("GITHUB_TOKEN")
-		if !tokenValidate(accessToken) {
-			return nil
+type WebhookTarget struct {
+	URL            string `json:"url" secret:"true"`
+	Secret         string `json:"secret" secret:"true"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	MaxRetries     int    `json:"max_retries"`
+	Format         string `json:"format"`
+	Template       string `json:"template"`
+	// ContentType of the request, application/json when empty.
+	ContentType string `json:"content_type"`
+}
+
+// NewConfig layers the config file at path (optional), the environment and
+// the flags that were set on the command line, later sources win. Problems
+// with the resulting values are reported by Validate.
+func NewConfig(path string, flags *Flags, tokenValidate func(conf *Config, token string) bool) (*Config, error) {
+	conf := &Config{}
+	if path != "" {
+		if err := loadFile(path, conf); err != nil {
+			return nil, err
+		}
+	}
+	if err := applyEnv(conf); err != nil {
+		return nil, err
+	}
+	if flags != nil {
+		flags.apply(conf)
+	}
+
+	// Actions always sets GITHUB_API_URL and GITHUB_GRAPHQL_URL, like
+	// GITHUB_TOKEN they only fill in for an endpoint that is not configured.
+	// They are taken as a pair, so a configured GHES api_url is not combined
+	// with the GraphQL endpoint of github.com.
+	if conf.APIURL == "" {
+		conf.APIURL = os.Getenv("GITHUB_API_URL")
+		if conf.GraphQLURL == "" {
+			conf.GraphQLURL = os.Getenv("GITHUB_GRAPHQL_URL")
+		}
+	}
+
+	// An explicit ACCESS_TOKEN wins over the file, the GITHUB_TOKEN provided
+	// by Actions is only a fallback since it is heavily rate limited.
+	// When no candidate is accepted the first configured one is kept, so
+	// Validate can explain why it was rejected.
+	candidates := []string{os.Getenv("ACCESS_TOKEN"), conf.AccessToken, os.Getenv("GITHUB_TOKEN")}
+	conf.AccessToken = ""
+	for _, token := range candidates {
+		if token == "" {
+			continue
+		}
+		if conf.AccessToken == "" {
+			conf.AccessToken = token
+		}
+		if tokenValidate(conf, token) {
+			conf.AccessToken = token
+			break
+		}
+	}
+
+	if len(conf.IncludeOwner) == 0 && conf.UserName != "" {
+		conf.IncludeOwner = []string{conf.UserName}
+	}
+
+	return conf, nil
+}
+
+// applyEnv overrides the fields whose environment variables are not empty.
+func applyEnv(conf *Config) error {
+	stringFromEnv := func(target *string, keys ...string) {
+		for _, key := range keys {
+			if value := os.Getenv(key); value != "" {
+				*target = value
+				return
+			}
 		}
 	}
 
-	userName := os.Getenv("CUSTOM_ACTOR")
-	if userName == "" {
-		userName = os.Getenv("GITHUB_ACTOR")
+	stringSliceFromEnv := func(target *[]string, key string) {
+		if value := os.Getenv(key); value != "" {
+			*target = splitList(value)
+		}
 	}
 
-	stringSliceFromEnv := func(key string) []string {
+	boolFromEnv := func(target *bool, key string) {
 		if value := os.Getenv(key); value != "" {
-			return strings.Split(value, ",")
+			*target = value == "true"
 		}
-		return []string{}
 	}
 
-	boolFromEnv := func(key string) bool {
-		return os.Getenv(key) == "true"
+	intFromEnv := func(target *int, key string) error {
+		if value := os.Getenv(key); value != "" {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			*target = i
+		}
+		return nil
 	}
 
-	conf := &Config{
-		UserName:    userName,
-		AccessToken: accessToken,
+	stringFromEnv(&conf.UserName, "CUSTOM_ACTOR")
+	if conf.UserName == "" {
+		stringFromEnv(&conf.UserName, "GITHUB_ACTOR")
+	}
+	stringFromEnv(&conf.APIURL, "API_URL")
+	stringFromEnv(&conf.GraphQLURL, "GRAPHQL_URL")
+
+	stringSliceFromEnv(&conf.ExcludeRepos, "EXCLUDE_REPOS")
+	stringSliceFromEnv(&conf.ExcludeLangs, "EXCLUDE_LANGS")
+	stringSliceFromEnv(&conf.IncludeOwner, "INCLUDE_OWNER")
+
+	boolFromEnv(&conf.IgnorePrivateRepos, "IGNORE_PRIVATE_REPOS")
+	boolFromEnv(&conf.IgnoreForkedRepos, "IGNORE_FORKED_REPOS")
+	boolFromEnv(&conf.IgnoreArchivedRepos, "IGNORE_ARCHIVED_REPOS")
+	boolFromEnv(&conf.IgnoreContributedToRepos, "IGNORE_CONTRIBUTED_TO_REPOS")
+
+	boolFromEnv(&conf.IgnoreLinesChanged, "IGNORE_LINES_CHANGED")
+	boolFromEnv(&conf.IgnoreRepoViews, "IGNORE_REPO_VIEWS")
+	boolFromEnv(&conf.IgnorePinnedRepos, "IGNORE_PINNED_REPOS")
 
-		ExcludeRepos: stringSliceFromEnv("EXCLUDE_REPOS"),
-		ExcludeLangs: stringSliceFromEnv("EXCLUDE_LANGS"),
-		IncludeOwner: stringSliceFromEnv("INCLUDE_OWNER"),
+	stringFromEnv(&conf.ErrorPolicy, "ERROR_POLICY")
 
-		IgnorePrivateRepos:       boolFromEnv("IGNORE_PRIVATE_REPOS"),
-		IgnoreForkedRepos:        boolFromEnv("IGNORE_FORKED_REPOS"),
-		IgnoreArchivedRepos:      boolFromEnv("IGNORE_ARCHIVED_REPOS"),
-		IgnoreContributedToRepos: boolFromEnv("IGNORE_CONTRIBUTED_TO_REPOS"),
+	stringFromEnv(&conf.Window, "WINDOW")
+	stringFromEnv(&conf.Since, "SINCE")
+	stringFromEnv(&conf.Until, "UNTIL")
 
-		IgnoreLinesChanged: boolFromEnv("IGNORE_LINES_CHANGED"),
-		IgnoreRepoViews:    boolFromEnv("IGNORE_REPO_VIEWS"),
+	stringFromEnv(&conf.StreakTimezone, "STREAK_TIMEZONE")
+	boolFromEnv(&conf.StreakSkipWeekends, "STREAK_SKIP_WEEKENDS")
 
-		Animation:  boolFromEnv("ANIMATION"),
-		WebhookURL: os.Getenv("WEBHOOK_URL"),
+	boolFromEnv(&conf.DisableHistory, "DISABLE_HISTORY")
+	if err := intFromEnv(&conf.DeltaDays, "DELTA_DAYS"); err != nil {
+		return err
 	}
 
-	if len(conf.IncludeOwner) == 0 {
-		conf.IncludeOwner = []string{conf.UserName}
+	stringFromEnv(&conf.CacheDir, "CACHE_DIR")
+	if err := intFromEnv(&conf.GraphQLCacheTTLSeconds, "GRAPHQL_CACHE_TTL_SECONDS"); err != nil {
+		return err
+	}
+
+	boolFromEnv(&conf.Animation, "ANIMATION")
+	stringFromEnv(&conf.CalendarTheme, "CALENDAR_THEME")
+	stringFromEnv(&conf.TopReposBy, "TOP_REPOS_BY")
+	if err := intFromEnv(&conf.TopReposCount, "TOP_REPOS_COUNT"); err != nil {
+		return err
+	}
+	stringFromEnv(&conf.TemplateDir, "TEMPLATE_DIR")
+
+	stringFromEnv(&conf.AppID, "APP_ID")
+	stringFromEnv(&conf.AppPrivateKey, "APP_PRIVATE_KEY")
+	stringFromEnv(&conf.AppPrivateKeyPath, "APP_PRIVATE_KEY_PATH")
+	if value := os.Getenv("APP_INSTALLATION_ID"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid APP_INSTALLATION_ID: %w", err)
+		}
+		conf.AppInstallationID = id
+	}
+
+	webhooks, err := webhooksFromEnv()
+	if err != nil {
+		return err
+	}
+	if webhooks != nil {
+		conf.Webhooks = webhooks
 	}
+	return nil
+}
+
+// splitList splits a comma separated value, trimming spaces and dropping
+// empty entries.
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
 
-	return conf
+// UsesApp reports whether GitHub App installation authentication is
+// configured, it takes precedence over the access token.
+func (c *Config) UsesApp() bool {
+	return c.AppID != "" || c.AppInstallationID != 0 || c.AppPrivateKey != "" || c.AppPrivateKeyPath != ""
+}
+
+// AppPrivateKeyPEM returns the app private key, read from AppPrivateKeyPath
+// when it is not set inline.
+func (c *Config) AppPrivateKeyPEM() ([]byte, error) {
+	if c.AppPrivateKey != "" {
+		return []byte(c.AppPrivateKey), nil
+	}
+	return os.ReadFile(c.AppPrivateKeyPath)
+}
+
+// webhooksFromEnv combines the targets listed in WEBHOOK_URL, which share
+// WEBHOOK_SECRET, with the JSON array in WEBHOOKS for per-target settings.
+func webhooksFromEnv() ([]WebhookTarget, error) {
+	var targets []WebhookTarget
+	for _, url := range splitList(os.Getenv("WEBHOOK_URL")) {
+		targets = append(targets, WebhookTarget{
+			URL:    url,
+			Secret: os.Getenv("WEBHOOK_SECRET"),
+			Format: os.Getenv("WEBHOOK_FORMAT"),
+		})
+	}
+	if value := os.Getenv("WEBHOOKS"); value != "" {
+		var extra []WebhookTarget
+		if err := json.Unmarshal([]byte(value), &extra); err != nil {
+			return nil, fmt.Errorf("invalid WEBHOOKS: %w", err)
+		}
+		targets = append(targets, extra...)
+	}
+	return targets, nil
+}
+
+// Redacted returns the config as a JSON ready map without the fields tagged
+// `secret:"true"`, nested structs and slices are redacted as well.
+func (c Config) Redacted() map[string]any {
+	redacted, _ := redact(reflect.ValueOf(c)).(map[string]any)
+	return redacted
+}
+
+// MarshalJSON always serializes the redacted view, so secrets can not leak
+// through logs or outbound payloads by accident.
+func (c Config) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.Redacted())
+}
+
+func redact(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return redact(v.Elem())
+	case reflect.Struct:
+		fields := make(map[string]any)
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() || field.Tag.Get("secret") == "true" {
+				continue
+			}
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			fields[name] = redact(v.Field(i))
+		}
+		return fields
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		items := make([]any, v.Len())
+		for i := range items {
+			items[i] = redact(v.Index(i))
+		}
+		return items
+	default:
+		return v.Interface()
+	}
 }
@@ -0,0 +1,117 @@
+package config
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fillSecrets sets every string field tagged `secret:"true"` reachable from v
+// to a unique value and returns the values.
+func fillSecrets(v reflect.Value) []string {
+	var secrets []string
+	switch v.Kind() {
+	case reflect.Pointer:
+		return fillSecrets(v.Elem())
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).Tag.Get("secret") == "true" {
+				secret := fmt.Sprintf("secret-%s-%p", v.Type().Field(i).Name, v.Field(i).Addr().Interface())
+				v.Field(i).SetString(secret)
+				secrets = append(secrets, secret)
+				continue
+			}
+			secrets = append(secrets, fillSecrets(v.Field(i))...)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			secrets = append(secrets, fillSecrets(v.Index(i))...)
+		}
+	}
+	return secrets
+}
+
+func TestRedactedHidesSecrets(t *testing.T) {
+	conf := &Config{UserName: "bob", Webhooks: []WebhookTarget{{Format: "slack"}, {Format: "json"}}}
+	secrets := fillSecrets(reflect.ValueOf(conf))
+	if len(secrets) != 6 {
+		t.Fatalf("filled %d secrets, want 6", len(secrets))
+	}
+
+	marshalled, err := json.Marshal(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	redacted, err := json.Marshal(conf.Redacted())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, data := range [][]byte{marshalled, redacted} {
+		for _, secret := range secrets {
+			if strings.Contains(string(data), secret) {
+				t.Errorf("%s leaks %s", data, secret)
+			}
+		}
+		if !strings.Contains(string(data), `"user_name":"bob"`) || !strings.Contains(string(data), `"format":"slack"`) {
+			t.Errorf("%s lost the public fields", data)
+		}
+	}
+}
+
+func TestNewConfigPrecedence(t *testing.T) {
+	for _, key := range []string{"ACCESS_TOKEN", "GITHUB_TOKEN", "CUSTOM_ACTOR", "GITHUB_ACTOR", "API_URL", "GRAPHQL_URL", "EXCLUDE_REPOS", "WINDOW"} {
+		t.Setenv(key, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.json")
+	file := `{"user_name":"file","access_token":"ghp_file","api_url":"https://ghes.example.com/api/v3","window":"30d","top_repos_by":"stars"}`
+	if err := os.WriteFile(path, []byte(file), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GITHUB_API_URL", "https://api.github.com")
+	t.Setenv("GITHUB_GRAPHQL_URL", "https://api.github.com/graphql")
+	t.Setenv("GITHUB_TOKEN", "ghp_actions")
+	t.Setenv("GITHUB_ACTOR", "actions")
+	t.Setenv("WINDOW", "90d")
+	t.Setenv("EXCLUDE_REPOS", "env/a")
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags := BindFlags(fs)
+	if err := fs.Parse([]string{"-exclude-repos", "flag/a,flag/b"}); err != nil {
+		t.Fatal(err)
+	}
+	conf, err := NewConfig(path, flags, func(*Config, string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name      string
+		got, want any
+	}{
+		{"file over GITHUB_ACTOR", conf.UserName, "file"},
+		{"file over GITHUB_TOKEN", conf.AccessToken, "ghp_file"},
+		{"file over GITHUB_API_URL", conf.APIURL, "https://ghes.example.com/api/v3"},
+		{"no GITHUB_GRAPHQL_URL with a configured api_url", conf.GraphQLURL, ""},
+		{"env over file", conf.Window, "90d"},
+		{"file without env or flag", conf.TopReposBy, "stars"},
+		{"flag over env", strings.Join(conf.ExcludeRepos, ","), "flag/a,flag/b"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+
+	// Without any configured endpoint the Actions variables fill in.
+	conf, err = NewConfig("", nil, func(*Config, string) bool { return true })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.APIURL != "https://api.github.com" || conf.GraphQLURL != "https://api.github.com/graphql" || conf.UserName != "actions" || conf.AccessToken != "ghp_actions" {
+		t.Errorf("fallbacks = %q %q %q %q", conf.APIURL, conf.GraphQLURL, conf.UserName, conf.AccessToken)
+	}
+}
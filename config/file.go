@@ -0,0 +1,180 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadFile decodes a JSON or YAML config file into conf. YAML is converted to
+// JSON first so both formats share the json struct tags of Config.
+func loadFile(path string, conf *Config) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config file: %w", err)
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		if err = yaml.Unmarshal(data, &node); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if len(node.Content) == 0 {
+			return nil
+		}
+		doc, err := yamlValue(&node, reflect.TypeOf(Config{}))
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if data, err = json.Marshal(doc); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".json":
+	default:
+		return fmt.Errorf("%s: unsupported config file type, use .json, .yaml or .yml", path)
+	}
+	if err = checkKnownKeys(data, reflect.TypeOf(Config{}), ""); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(conf); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return fmt.Errorf("%s: key %q must be %s, got %s", path, typeErr.Field, describeType(typeErr.Type), typeErr.Value)
+		}
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// yamlValue converts node to a JSON ready value following the fields of typ.
+// Scalars of string fields keep their text, so unquoted values such as
+// 2026-07-01 or 0123 are not turned into timestamps or numbers on the way.
+func yamlValue(node *yaml.Node, typ reflect.Type) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return yamlValue(node.Content[0], typ)
+	case yaml.AliasNode:
+		return yamlValue(node.Alias, typ)
+	case yaml.MappingNode:
+		if typ.Kind() != reflect.Struct {
+			break
+		}
+		fields := jsonFields(typ)
+		object := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			var value any
+			var err error
+			if field, ok := fields[key]; ok {
+				value, err = yamlValue(node.Content[i+1], field.Type)
+			} else {
+				err = node.Content[i+1].Decode(&value)
+			}
+			if err != nil {
+				return nil, err
+			}
+			object[key] = value
+		}
+		return object, nil
+	case yaml.SequenceNode:
+		if typ.Kind() != reflect.Slice {
+			break
+		}
+		items := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := yamlValue(item, typ.Elem())
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+	case yaml.ScalarNode:
+		if typ.Kind() == reflect.String && node.Tag != "!!null" {
+			return node.Value, nil
+		}
+	}
+	var value any
+	err := node.Decode(&value)
+	return value, err
+}
+
+// checkKnownKeys walks objects and arrays of objects so an unknown key is
+// reported with its full path and the keys that are accepted there.
+func checkKnownKeys(data []byte, typ reflect.Type, prefix string) error {
+	switch typ.Kind() {
+	case reflect.Slice:
+		var items []json.RawMessage
+		if json.Unmarshal(data, &items) != nil {
+			return nil
+		}
+		for i, item := range items {
+			if err := checkKnownKeys(item, typ.Elem(), fmt.Sprintf("%s[%d]", prefix, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Struct:
+		var object map[string]json.RawMessage
+		if json.Unmarshal(data, &object) != nil {
+			return nil
+		}
+		fields := jsonFields(typ)
+		for key, value := range object {
+			path := key
+			if prefix != "" {
+				path = prefix + "." + key
+			}
+			field, ok := fields[key]
+			if !ok {
+				known := make([]string, 0, len(fields))
+				for name := range fields {
+					known = append(known, name)
+				}
+				slices.Sort(known)
+				return fmt.Errorf("unknown key %q, expected one of: %s", path, strings.Join(known, ", "))
+			}
+			if err := checkKnownKeys(value, field.Type, path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func jsonFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if !field.IsExported() || name == "-" || name == "" {
+			continue
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+func describeType(typ reflect.Type) string {
+	switch typ.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Slice:
+		return "a list"
+	default:
+		return "an object"
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestLoadFileYAMLScalars(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+user_name: 0123
+since: 2026-07-01
+until: 2026-09-30
+exclude_langs: [1C, 0x10]
+top_repos_count: 7
+animation: true
+webhooks:
+  - url: https://example.com/hook
+    secret: 00123
+    max_retries: 2
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	var conf Config
+	if err := loadFile(path, &conf); err != nil {
+		t.Fatalf("loadFile: %v", err)
+	}
+	if conf.UserName != "0123" {
+		t.Errorf("UserName = %q, want 0123", conf.UserName)
+	}
+	if conf.Since != "2026-07-01" || conf.Until != "2026-09-30" {
+		t.Errorf("Since, Until = %q, %q, want 2026-07-01, 2026-09-30", conf.Since, conf.Until)
+	}
+	if want := []string{"1C", "0x10"}; !slices.Equal(conf.ExcludeLangs, want) {
+		t.Errorf("ExcludeLangs = %q, want %q", conf.ExcludeLangs, want)
+	}
+	if conf.TopReposCount != 7 || !conf.Animation {
+		t.Errorf("TopReposCount, Animation = %d, %v, want 7, true", conf.TopReposCount, conf.Animation)
+	}
+	if len(conf.Webhooks) != 1 || conf.Webhooks[0].Secret != "00123" || conf.Webhooks[0].MaxRetries != 2 {
+		t.Errorf("Webhooks = %+v, want secret 00123 and 2 retries", conf.Webhooks)
+	}
+	for _, err := range Validate(&conf, nil) {
+		if diagnostic := err.(*Diagnostic); diagnostic.Field == "since" || diagnostic.Field == "until" {
+			t.Errorf("Validate: %v", err)
+		}
+	}
+}
+
+func TestLoadFileYAMLErrors(t *testing.T) {
+	for name, content := range map[string]string{
+		"unknown key": "user_nmae: bob\n",
+		"wrong type":  "top_repos_count: many\n",
+	} {
+		path := filepath.Join(t.TempDir(), "config.yml")
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := loadFile(path, &Config{}); err == nil {
+			t.Errorf("%s: loadFile accepted %q", name, content)
+		}
+	}
+}
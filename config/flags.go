@@ -0,0 +1,99 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Flags exposes the plain config fields as command line flags, e.g.
+// exclude_repos becomes -exclude-repos. Secret fields are left out on purpose
+// so tokens never end up in the process list or shell history.
+type Flags struct {
+	fs     *flag.FlagSet
+	values map[string]*fieldFlag
+}
+
+func BindFlags(fs *flag.FlagSet) *Flags {
+	flags := &Flags{
+		fs:     fs,
+		values: make(map[string]*fieldFlag),
+	}
+	typ := reflect.TypeOf(Config{})
+	for name, field := range jsonFields(typ) {
+		if field.Tag.Get("secret") == "true" {
+			continue
+		}
+		switch field.Type.Kind() {
+		case reflect.String, reflect.Bool, reflect.Int, reflect.Int64:
+		case reflect.Slice:
+			if field.Type.Elem().Kind() != reflect.String {
+				continue
+			}
+		default:
+			continue
+		}
+		flagName := strings.ReplaceAll(name, "_", "-")
+		value := &fieldFlag{field: field}
+		flags.values[flagName] = value
+		fs.Var(value, flagName, fmt.Sprintf("Overrides %q from the config file and environment", name))
+	}
+	return flags
+}
+
+func (f *Flags) apply(conf *Config) {
+	target := reflect.ValueOf(conf).Elem()
+	f.fs.Visit(func(fl *flag.Flag) {
+		if value, ok := f.values[fl.Name]; ok && value.set {
+			target.FieldByIndex(value.field.Index).Set(value.value)
+		}
+	})
+}
+
+type fieldFlag struct {
+	field reflect.StructField
+	value reflect.Value
+	set   bool
+}
+
+func (f *fieldFlag) String() string {
+	if f == nil || !f.set {
+		return ""
+	}
+	return fmt.Sprint(f.value.Interface())
+}
+
+func (f *fieldFlag) Set(s string) error {
+	switch f.field.Type.Kind() {
+	case reflect.String:
+		f.value = reflect.ValueOf(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		f.value = reflect.ValueOf(b)
+	case reflect.Int:
+		i, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		f.value = reflect.ValueOf(i)
+	case reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return err
+		}
+		f.value = reflect.ValueOf(i)
+	case reflect.Slice:
+		f.value = reflect.ValueOf(splitList(s))
+	}
+	f.set = true
+	return nil
+}
+
+func (f *fieldFlag) IsBoolFlag() bool {
+	return f.field.Type.Kind() == reflect.Bool
+}
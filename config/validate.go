@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Diagnostic describes one problem found by Validate together with the steps
+// that fix it. Warnings only disable a feature, the run can still proceed.
+type Diagnostic struct {
+	Field       string
+	Problem     string
+	Remediation string
+	Warning     bool
+}
+
+func (d *Diagnostic) Error() string {
+	return fmt.Sprintf("%s: %s; %s", d.Field, d.Problem, d.Remediation)
+}
+
+// ErrTokenRejected is wrapped by a TokenInspector when GitHub answered but did
+// not accept the token.
+var ErrTokenRejected = errors.New("token rejected")
+
+// TokenInspector checks a token against GitHub and returns the scopes granted
+// to it. Scopes are nil when GitHub does not report them.
+type TokenInspector func(conf *Config, token string) (scopes []string, err error)
+
+var (
+	loginPattern    = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+	repoPattern     = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})/[A-Za-z0-9._-]+$`)
+	webhookFormats  = []string{"", "json", "slack", "discord", "teams", "template"}
+	errorPolicies   = []string{"", "fail", "warn", "ignore"}
+	calendarThemes  = []string{"", "light", "dark"}
+	topReposRanks   = []string{"", "stars", "forks", "churn", "views"}
+	windowPresets   = []string{"", "last-30-days", "last-90-days", "last-365-days", "this-quarter", "last-quarter", "this-year", "last-year"}
+	createTokenHint = "create a personal access token with the repo scope at https://github.com/settings/tokens and set it as ACCESS_TOKEN"
+)
+
+// Validate returns every problem found in conf as a *Diagnostic, inspect may
+// be nil to skip the checks that need network access.
+func Validate(conf *Config, inspect TokenInspector) []error {
+	var errs []error
+	report := func(field, remediation, problem string, args ...any) {
+		errs = append(errs, &Diagnostic{
+			Field:       field,
+			Problem:     fmt.Sprintf(problem, args...),
+			Remediation: remediation,
+		})
+	}
+	warn := func(field, remediation, problem string, args ...any) {
+		report(field, remediation, problem, args...)
+		errs[len(errs)-1].(*Diagnostic).Warning = true
+	}
+
+	if conf.UserName == "" {
+		report("user_name", "set CUSTOM_ACTOR, user_name in the config file or -user-name", "no GitHub username configured")
+	} else if !loginPattern.MatchString(conf.UserName) {
+		report("user_name", "use the GitHub login, not the display name", "%q is not a valid GitHub username", conf.UserName)
+	}
+
+	for _, endpoint := range [][2]string{{"api_url", conf.APIURL}, {"graphql_url", conf.GraphQLURL}} {
+		if endpoint[1] != "" && !isHTTPURL(endpoint[1]) {
+			report(endpoint[0], "use an absolute URL such as https://github.example.com/api/v3", "%q is not a valid URL", endpoint[1])
+		}
+	}
+
+	for i, repo := range conf.ExcludeRepos {
+		if !repoPattern.MatchString(repo) {
+			report(fmt.Sprintf("exclude_repos[%d]", i), "write repositories as owner/name", "%q is not a repository name", repo)
+		}
+	}
+	for i, lang := range conf.ExcludeLangs {
+		if strings.TrimSpace(lang) == "" {
+			report(fmt.Sprintf("exclude_langs[%d]", i), "remove the empty entry", "empty language name")
+		}
+	}
+	for i, owner := range conf.IncludeOwner {
+		if !loginPattern.MatchString(owner) {
+			report(fmt.Sprintf("include_owner[%d]", i), "list user or organization logins without the repository part", "%q is not a valid owner", owner)
+		}
+	}
+
+	if !slices.Contains(errorPolicies, conf.ErrorPolicy) {
+		report("error_policy", "use one of fail, warn or ignore", "unknown error policy %q", conf.ErrorPolicy)
+	}
+
+	if !slices.Contains(windowPresets, conf.Window) {
+		report("window", "use one of "+strings.Join(windowPresets[1:], ", "), "unknown window %q", conf.Window)
+	}
+	if conf.Window != "" && (conf.Since != "" || conf.Until != "") {
+		report("window", "set either window or since and until", "window can not be combined with since or until")
+	}
+	since, sinceErr := time.Parse(time.DateOnly, conf.Since)
+	if conf.Since != "" && sinceErr != nil {
+		report("since", "write dates as YYYY-MM-DD", "%q is not a date", conf.Since)
+	}
+	until, untilErr := time.Parse(time.DateOnly, conf.Until)
+	if conf.Until != "" && untilErr != nil {
+		report("until", "write dates as YYYY-MM-DD", "%q is not a date", conf.Until)
+	}
+	if conf.Until != "" && conf.Since == "" {
+		report("since", "set since to the first day of the window", "until needs a since date")
+	}
+	if sinceErr == nil && untilErr == nil {
+		if until.Before(since) {
+			report("until", "set until on or after since", "the window ends before it starts")
+		} else if until.After(since.AddDate(1, 0, -1)) {
+			report("until", "split longer periods into windows of at most one year", "GitHub only counts contributions for up to one year at once")
+		}
+	} else if sinceErr == nil && conf.Until == "" && time.Now().After(since.AddDate(1, 0, 0)) {
+		// Without until the window ends today.
+		report("since", "set since within the last year or add an until date", "GitHub only counts contributions for up to one year at once")
+	}
+
+	if _, err := time.LoadLocation(conf.StreakTimezone); err != nil {
+		report("streak_timezone", "use an IANA timezone name such as Europe/Berlin", "unknown timezone %q", conf.StreakTimezone)
+	}
+
+	if !slices.Contains(calendarThemes, conf.CalendarTheme) {
+		report("calendar_theme", "use light or dark", "unknown calendar theme %q", conf.CalendarTheme)
+	}
+
+	if !slices.Contains(topReposRanks, conf.TopReposBy) {
+		report("top_repos_by", "use one of stars, forks, churn or views", "unknown ranking %q", conf.TopReposBy)
+	}
+	if conf.TopReposCount < 0 {
+		report("top_repos_count", "use a positive number, or 0 for the default of 5", "%d is negative", conf.TopReposCount)
+	}
+
+	if conf.TemplateDir != "" {
+		if info, err := os.Stat(conf.TemplateDir); err != nil || !info.IsDir() {
+			report("template_dir", "point template_dir to a directory of *.gohtml files", "%q is not a directory", conf.TemplateDir)
+		}
+	}
+
+	if conf.DeltaDays < 0 {
+		report("delta_days", "use a positive number of days, or 0 for the default of 7", "%d is negative", conf.DeltaDays)
+	}
+
+	for i, target := range conf.Webhooks {
+		field := fmt.Sprintf("webhooks[%d]", i)
+		if !isHTTPURL(target.URL) {
+			report(field+".url", "use an absolute http or https URL", "invalid webhook URL")
+		}
+		if !slices.Contains(webhookFormats, strings.ToLower(target.Format)) {
+			report(field+".format", "use one of json, slack, discord, teams or template", "unknown format %q", target.Format)
+		}
+		if strings.EqualFold(target.Format, "template") && target.Template == "" {
+			report(field+".template", "set template to the path of a text/template file", "the template format needs a template file")
+		}
+		if target.ContentType != "" {
+			if _, _, err := mime.ParseMediaType(target.ContentType); err != nil {
+				report(field+".content_type", "use a media type like text/plain; charset=utf-8", "invalid content type %q", target.ContentType)
+			}
+		}
+	}
+
+	if conf.UsesApp() {
+		reported := len(errs)
+		if conf.AppID == "" {
+			report("app_id", "set APP_ID to the ID shown on the GitHub App settings page", "GitHub App authentication needs an app ID")
+		}
+		if conf.AppInstallationID == 0 {
+			report("app_installation_id", "set APP_INSTALLATION_ID to the number at the end of the installation settings URL", "GitHub App authentication needs an installation ID")
+		}
+		if conf.AppPrivateKey == "" && conf.AppPrivateKeyPath == "" {
+			report("app_private_key", "set APP_PRIVATE_KEY to the PEM contents or APP_PRIVATE_KEY_PATH to the downloaded .pem file", "GitHub App authentication needs the app private key")
+		} else if _, err := conf.AppPrivateKeyPEM(); err != nil {
+			report("app_private_key_path", "check that the .pem file exists and is readable", "could not read the private key: %v", err)
+		}
+		if len(errs) > reported {
+			return errs
+		}
+	} else if conf.AccessToken == "" {
+		report("access_token", createTokenHint, "no access token configured")
+		return errs
+	}
+	if inspect == nil {
+		return errs
+	}
+	scopes, err := inspect(conf, conf.AccessToken)
+	if errors.Is(err, ErrTokenRejected) && conf.UsesApp() {
+		report("app_private_key", "check app_id, app_installation_id and that the key belongs to the app", "GitHub rejected the app credentials")
+		return errs
+	}
+	if errors.Is(err, ErrTokenRejected) {
+		report("access_token", createTokenHint+", or check api_url when using GitHub Enterprise Server", "GitHub rejected the access token")
+		return errs
+	}
+	if err != nil {
+		report("access_token", "check the network connection and api_url", "could not verify the access token: %v", err)
+		return errs
+	}
+	if scopes != nil && !slices.Contains(scopes, "repo") {
+		if !conf.IgnoreRepoViews && !slices.Contains(scopes, "public_repo") {
+			warn("access_token", "grant the repo scope or set IGNORE_REPO_VIEWS=true", "repository traffic needs the repo or public_repo scope, granted scopes: %s", formatScopes(scopes))
+		}
+		if !conf.IgnorePrivateRepos {
+			warn("access_token", "grant the repo scope or set IGNORE_PRIVATE_REPOS=true", "private repositories need the repo scope, granted scopes: %s", formatScopes(scopes))
+		}
+	}
+	return errs
+}
+
+func formatScopes(scopes []string) string {
+	if len(scopes) == 0 {
+		return "none"
+	}
+	return strings.Join(scopes, ", ")
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// IsWarning reports whether err is a Diagnostic that does not block a run.
+func IsWarning(err error) bool {
+	var diagnostic *Diagnostic
+	return errors.As(err, &diagnostic) && diagnostic.Warning
+}
@@ -0,0 +1,35 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidateWindowLength(t *testing.T) {
+	recent := time.Now().AddDate(0, -6, 0).Format(time.DateOnly)
+	tests := []struct {
+		name         string
+		since, until string
+		field        string
+	}{
+		{"since within a year", recent, "", ""},
+		{"since over a year ago", "2020-01-01", "", "since"},
+		{"since and until within a year", "2020-01-01", "2020-12-31", ""},
+		{"since and until over a year", "2020-01-01", "2021-01-01", "until"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &Config{UserName: "bob", AccessToken: "ghp_x", Since: tt.since, Until: tt.until}
+			var fields []string
+			for _, err := range Validate(conf, nil) {
+				fields = append(fields, err.(*Diagnostic).Field)
+			}
+			switch {
+			case tt.field == "" && len(fields) > 0:
+				t.Errorf("unexpected problems with %v", fields)
+			case tt.field != "" && (len(fields) != 1 || fields[0] != tt.field):
+				t.Errorf("problems with %v, want %s", fields, tt.field)
+			}
+		})
+	}
+}
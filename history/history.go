@@ -0,0 +1,186 @@
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+// FileName is the snapshot file created in the output directory.
+const FileName = "history.jsonl"
+
+type Snapshot struct {
+	Time    time.Time `json:"time"`
+	Metrics Metrics   `json:"stats"`
+}
+
+// Metrics are the parts of stats.Stats that stats.Diff compares. The JSON
+// names match stats.Stats, so snapshots of the full stats written by earlier
+// versions still load.
+type Metrics struct {
+	Stargazers    int                       `json:"stargazers"`
+	Forks         int                       `json:"forks"`
+	Contributions *stats.ContributionsStats `json:"contributions,omitempty"`
+	LineChange    *stats.LineChangeStats    `json:"lineChange,omitempty"`
+	Languages     map[string]languageSize   `json:"languages,omitempty"`
+}
+
+type languageSize struct {
+	Size int `json:"size"`
+}
+
+func NewMetrics(stat *stats.Stats) Metrics {
+	metrics := Metrics{
+		Stargazers:    stat.Stargazers,
+		Forks:         stat.Forks,
+		Contributions: stat.Contributions,
+		LineChange:    stat.LineChange,
+	}
+	if len(stat.Languages) > 0 {
+		metrics.Languages = make(map[string]languageSize, len(stat.Languages))
+		for name, lang := range stat.Languages {
+			metrics.Languages[name] = languageSize{Size: lang.Size}
+		}
+	}
+	return metrics
+}
+
+// Stats returns the metrics as stats.Stats for stats.Diff.
+func (m Metrics) Stats() *stats.Stats {
+	stat := &stats.Stats{
+		Stargazers:    m.Stargazers,
+		Forks:         m.Forks,
+		Contributions: m.Contributions,
+		LineChange:    m.LineChange,
+		Languages:     make(map[string]*stats.LanguageStats, len(m.Languages)),
+	}
+	for name, lang := range m.Languages {
+		stat.Languages[name] = &stats.LanguageStats{Name: name, Size: lang.Size}
+	}
+	return stat
+}
+
+// Store keeps one JSON encoded Snapshot per line, oldest first.
+type Store struct {
+	path string
+}
+
+func NewStore(dir string) *Store {
+	return &Store{path: filepath.Join(dir, FileName)}
+}
+
+func (s *Store) Append(snapshot Snapshot) error {
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(data, '\n')); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Load returns every snapshot, a missing file is an empty history.
+func (s *Store) Load() ([]Snapshot, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+	var snapshots []Snapshot
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var snapshot Snapshot
+		if err = json.Unmarshal(scanner.Bytes(), &snapshot); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", s.path, line, err)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// Baseline returns the newest snapshot taken at or before t, or the oldest
+// one when the history does not reach back that far. It returns nil for an
+// empty history.
+func (s *Store) Baseline(t time.Time) (*Snapshot, error) {
+	snapshots, err := s.Load()
+	if err != nil || len(snapshots) == 0 {
+		return nil, err
+	}
+	baseline := snapshots[0]
+	for _, snapshot := range snapshots {
+		if snapshot.Time.After(t) {
+			break
+		}
+		baseline = snapshot
+	}
+	return &baseline, nil
+}
+
+// Prune drops the snapshots older than the baseline for t, they can not be
+// the baseline of a later run with the same delta. The file is replaced
+// atomically and only when something was dropped.
+func (s *Store) Prune(t time.Time) error {
+	snapshots, err := s.Load()
+	if err != nil {
+		return err
+	}
+	keep := 0
+	for i, snapshot := range snapshots {
+		if snapshot.Time.After(t) {
+			break
+		}
+		keep = i
+	}
+	if keep == 0 {
+		return nil
+	}
+	var data []byte
+	for _, snapshot := range snapshots[keep:] {
+		line, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		data = append(append(data, line...), '\n')
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), FileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,95 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+func TestStoreKeepsOnlyMetrics(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir)
+	stat := &stats.Stats{
+		Name:          "bob",
+		Stargazers:    13,
+		Forks:         3,
+		Contributions: &stats.ContributionsStats{TotalContributions: 200},
+		LineChange:    &stats.LineChangeStats{Additions: 2200, Deletions: 1040},
+		Languages:     map[string]*stats.LanguageStats{"Go": {Name: "Go", Size: 4096, Color: "#00ADD8"}},
+		Repos:         map[string]*stats.RepoStats{"bob/alpha": {Name: "bob/alpha"}},
+	}
+	if err := store.Append(Snapshot{Time: time.Now(), Metrics: NewMetrics(stat)}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range []string{"bob/alpha", "#00ADD8", `"name"`} {
+		if strings.Contains(string(data), field) {
+			t.Errorf("snapshot stores %s: %s", field, data)
+		}
+	}
+
+	snapshots, err := store.Load()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("Load = %d snapshots, %v", len(snapshots), err)
+	}
+	if delta := stats.Diff(snapshots[0].Metrics.Stats(), stat); *delta.Contributions != 0 || *delta.LinesChanged != 0 || len(delta.Languages) != 0 {
+		t.Errorf("Diff with the stored snapshot = %+v, want no change", delta)
+	}
+}
+
+func TestStoreLoadsFullStats(t *testing.T) {
+	dir := t.TempDir()
+	line := `{"time":"2026-10-01T00:00:00Z","stats":{"name":"bob","stargazers":10,"forks":2,"languages":{"Go":{"name":"Go","size":100,"color":"#00ADD8"}},"repos":{"bob/alpha":{"name":"bob/alpha"}},"contributions":{"totalContributions":150},"lineChange":{"additions":10,"deletions":5,"commits":1},"views":null}}` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(line), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := NewStore(dir).Load()
+	if err != nil || len(snapshots) != 1 {
+		t.Fatalf("Load = %d snapshots, %v", len(snapshots), err)
+	}
+	metrics := snapshots[0].Metrics
+	if metrics.Stargazers != 10 || metrics.Forks != 2 || metrics.Contributions.TotalContributions != 150 ||
+		metrics.LineChange.Additions != 10 || metrics.Languages["Go"].Size != 100 {
+		t.Errorf("Metrics = %+v", metrics)
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := NewStore(t.TempDir())
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	for i := range 10 {
+		if err := store.Append(Snapshot{Time: day.AddDate(0, 0, i), Metrics: Metrics{Stargazers: i}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cutoff := day.AddDate(0, 0, 6).Add(time.Hour)
+	if err := store.Prune(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	snapshots, err := store.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshots) != 4 || snapshots[0].Metrics.Stargazers != 6 {
+		t.Fatalf("kept %d snapshots starting with %+v, want 4 starting with day 6", len(snapshots), snapshots[0])
+	}
+	baseline, err := store.Baseline(cutoff)
+	if err != nil || baseline.Metrics.Stargazers != 6 {
+		t.Errorf("Baseline after Prune = %+v, %v, want day 6", baseline, err)
+	}
+
+	// Nothing older than the baseline is left.
+	if err = store.Prune(cutoff); err != nil {
+		t.Fatal(err)
+	}
+	if snapshots, _ = store.Load(); len(snapshots) != 4 {
+		t.Errorf("second Prune kept %d snapshots, want 4", len(snapshots))
+	}
+}
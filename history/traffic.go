@@ -0,0 +1,66 @@
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+// TrafficFileName is the accumulated traffic file created in the output
+// directory.
+const TrafficFileName = "traffic.json"
+
+// TrafficStore keeps the daily traffic of every repository in one JSON file,
+// it implements stats.TrafficStore.
+type TrafficStore struct {
+	path string
+}
+
+func NewTrafficStore(dir string) *TrafficStore {
+	return &TrafficStore{path: filepath.Join(dir, TrafficFileName)}
+}
+
+func (s *TrafficStore) LoadTraffic() (map[string]*stats.TrafficHistory, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var history map[string]*stats.TrafficHistory
+	if err = json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	return history, nil
+}
+
+// SaveTraffic replaces the file atomically, an interrupted run keeps the
+// previous history intact.
+func (s *TrafficStore) SaveTraffic(history map[string]*stats.TrafficHistory) error {
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), TrafficFileName+".*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
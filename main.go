@@ -1,76 +1,336 @@
 package main
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
-	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/TBXark/github-status/config"
+	"github.com/TBXark/github-status/history"
 	"github.com/TBXark/github-status/query"
 	"github.com/TBXark/github-status/render"
 	"github.com/TBXark/github-status/stats"
+	"github.com/TBXark/github-status/webhook"
 )
 
 func main() {
-	if err := run(); err != nil {
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		err = validate(os.Args[2:])
+	} else {
+		err = run(os.Args[1:])
+	}
+	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 }
 
-func run() error {
-	output := flag.String("output", "output", "The output directory")
-	debug := flag.Bool("debug", false, "Enable debug mode")
-	flag.Parse()
+type configLoader func() (*config.Config, []query.Option, error)
 
-	conf := config.NewConfig(func(token string) bool {
-		return query.NewQueries(token).IsValid()
-	})
-	if conf == nil {
-		return fmt.Errorf("invalid config")
+// bindConfig registers the flags shared by all commands. The loader returns
+// the config together with the query options every API client has to use,
+// the tokens it tries are looked up through tokens.
+func bindConfig(fs *flag.FlagSet, tokens *tokenInfos) configLoader {
+	configPath := fs.String("config", "", "Path to a JSON or YAML config file")
+	record := fs.String("record", "", "Record GitHub API responses as fixtures into this directory")
+	replay := fs.String("replay", "", "Replay GitHub API responses from the fixtures in this directory")
+	configFlags := config.BindFlags(fs)
+	return func() (*config.Config, []query.Option, error) {
+		var base []query.Option
+		switch {
+		case *record != "" && *replay != "":
+			return nil, nil, fmt.Errorf("-record and -replay can not be combined")
+		case *record != "":
+			base = append(base, query.WithTransport(&query.RecordTransport{Dir: *record}))
+		case *replay != "":
+			base = append(base, query.WithTransport(&query.ReplayTransport{Dir: *replay}))
+		}
+		conf, err := config.NewConfig(*configPath, configFlags, func(conf *config.Config, token string) bool {
+			options := append(slices.Clone(base), query.WithEndpoints(conf.APIURL, conf.GraphQLURL))
+			if conf.CacheDir != "" {
+				options = append(options, query.WithCache(conf.CacheDir, time.Duration(conf.GraphQLCacheTTLSeconds)*time.Second))
+			}
+			_, err := tokens.lookup("token "+token, query.NewQueries(token, options...))
+			return err == nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		options, err := queryOptions(conf, base...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return conf, options, nil
+	}
+}
+
+// tokenInfos remembers the TokenInfo of every token looked up at startup, so
+// choosing the token, validating the config and loading the stats share one
+// request per token.
+type tokenInfos struct {
+	results map[string]tokenLookup
+	// inspected is the info of the token the config was validated with.
+	inspected *query.TokenInfo
+}
+
+type tokenLookup struct {
+	info *query.TokenInfo
+	err  error
+}
+
+func newTokenInfos() *tokenInfos {
+	return &tokenInfos{results: make(map[string]tokenLookup)}
+}
+
+func (t *tokenInfos) lookup(key string, queries *query.Queries) (*query.TokenInfo, error) {
+	if result, ok := t.results[key]; ok {
+		return result.info, result.err
 	}
-	loader := stats.NewStats(
-		conf.UserName,
-		conf.AccessToken,
+	info, err := queries.TokenInfo(context.Background())
+	t.results[key] = tokenLookup{info: info, err: err}
+	return info, err
+}
+
+func (t *tokenInfos) inspector(options []query.Option) config.TokenInspector {
+	return func(conf *config.Config, token string) ([]string, error) {
+		key := "token " + token
+		if conf.UsesApp() {
+			key = "app"
+		}
+		info, err := t.lookup(key, query.NewQueries(token, options...))
+		if errors.Is(err, query.ErrBadCredentials) {
+			return nil, fmt.Errorf("%w: %w", config.ErrTokenRejected, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.RateLimit.Limit > 0 {
+			log.Printf("Using %s token, %d/%d requests left until %s", info.Type, info.RateLimit.Remaining, info.RateLimit.Limit, info.RateLimit.Reset.Format(time.Kitchen))
+		}
+		t.inspected = info
+		return info.Scopes, nil
+	}
+}
+
+func validate(args []string) error {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	tokens := newTokenInfos()
+	loadConfig := bindConfig(fs, tokens)
+	_ = fs.Parse(args)
+
+	conf, options, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	failed := 0
+	for _, diagnostic := range config.Validate(conf, tokens.inspector(options)) {
+		if config.IsWarning(diagnostic) {
+			fmt.Printf("warning: %v\n", diagnostic)
+			continue
+		}
+		fmt.Printf("error: %v\n", diagnostic)
+		failed++
+	}
+	if failed > 0 {
+		return fmt.Errorf("config has %d problem(s)", failed)
+	}
+	fmt.Println("Config is valid")
+	return nil
+}
+
+func run(args []string) error {
+	fs := flag.NewFlagSet("github-status", flag.ExitOnError)
+	output := fs.String("output", "output", "The output directory")
+	debug := fs.Bool("debug", false, "Enable debug mode")
+	tokens := newTokenInfos()
+	loadConfig := bindConfig(fs, tokens)
+	_ = fs.Parse(args)
+
+	conf, options, err := loadConfig()
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	var problems []error
+	for _, diagnostic := range config.Validate(conf, tokens.inspector(options)) {
+		if config.IsWarning(diagnostic) {
+			log.Printf("Warning: %v", diagnostic)
+			continue
+		}
+		problems = append(problems, diagnostic)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid config, run the validate command for details:\n%w", errors.Join(problems...))
+	}
+	policy, err := stats.ParseErrorPolicy(conf.ErrorPolicy)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	window, err := statsWindow(conf)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	location, err := time.LoadLocation(conf.StreakTimezone)
+	if err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	loaderOptions := []stats.Option{
 		stats.IgnoreForkedRepos(conf.IgnoreForkedRepos),
 		stats.IgnoreArchivedRepos(conf.IgnoreArchivedRepos),
 		stats.IgnorePrivateRepos(conf.IgnorePrivateRepos),
 		stats.IgnoreContributedToRepos(conf.IgnoreContributedToRepos),
 		stats.IgnoreLinesChanged(conf.IgnoreLinesChanged),
 		stats.IgnoreRepoViews(conf.IgnoreRepoViews),
+		stats.IgnorePinnedRepos(conf.IgnorePinnedRepos),
 		stats.ExcludeRepos(conf.ExcludeRepos...),
 		stats.ExcludeLangs(conf.ExcludeLangs...),
 		stats.IncludeOwner(conf.IncludeOwner...),
-	)
+		stats.QueryOptions(options...),
+		stats.OnError(policy),
+		stats.WithWindow(window),
+		stats.StreakLocation(location),
+		stats.StreakSkipWeekends(conf.StreakSkipWeekends),
+	}
+	var templates *render.Templates
+	var renderOptions []render.Option
+	if conf.TemplateDir != "" {
+		templates, err = render.LoadTemplates(conf.TemplateDir)
+		if err != nil {
+			return fmt.Errorf("invalid templates: %w", err)
+		}
+		if err = checkExtraTemplates(templates); err != nil {
+			return fmt.Errorf("invalid templates: %w", err)
+		}
+		renderOptions = append(renderOptions, render.WithTemplates(templates))
+	}
+	if !conf.DisableHistory {
+		loaderOptions = append(loaderOptions, stats.WithTrafficStore(history.NewTrafficStore(*output)))
+	}
+	if tokens.inspected != nil {
+		loaderOptions = append(loaderOptions, stats.WithTokenInfo(tokens.inspected))
+	}
+	loader := stats.NewStats(conf.UserName, conf.AccessToken, loaderOptions...)
 	stat, err := loader.GetStats(context.Background())
 	if err != nil {
 		return fmt.Errorf("failed to get stats: %w", err)
 	}
-	if e := saveStat(conf.Animation, stat, *output); e != nil {
-		log.Printf("Failed to save stat: %v", e)
+	if !conf.DisableHistory {
+		delta, e := recordHistory(*output, stat, conf.DeltaDays)
+		if e != nil {
+			log.Printf("Failed to update history: %v", e)
+		} else if delta != nil {
+			renderOptions = append(renderOptions, render.WithDelta(delta))
+		}
 	}
-	if e := sendWebhook(conf, stat); e != nil {
-		log.Printf("Failed to send webhook: %v", e)
+	if e := saveStat(conf, stat, *output, templates, renderOptions...); e != nil {
+		log.Printf("Failed to save stat: %v", e)
 	}
+	results := sendWebhooks(conf, stat)
 	if *debug {
+		log.Printf("Recovered %d language bytes from %d extra language pages", stat.Debug.LanguageBytesRecovered, stat.Debug.LanguagePagesFetched)
 		data, _ := json.MarshalIndent(stat, "", "  ")
 		_ = os.WriteFile(*output+"/data.json", data, 0o644)
 	}
+	printSummary(stat, results)
 	return nil
 }
 
-func saveStat(animation bool, stat *stats.Stats, output string) error {
+func printSummary(stat *stats.Stats, webhooks []webhook.Result) {
+	log.Printf("Generated stats for %s: %d repositories, %d languages", stat.Name, len(stat.Repos), len(stat.Languages))
+	if len(stat.Errors) > 0 {
+		log.Printf("%d metric(s) could not be loaded, see the errors field of data.json", len(stat.Errors))
+	}
+	for _, result := range webhooks {
+		if result.Err != nil {
+			log.Printf("Webhook %s failed after %d attempt(s): %v", result.Target, result.Attempts, result.Err)
+		} else {
+			log.Printf("Webhook %s delivered after %d attempt(s)", result.Target, result.Attempts)
+		}
+	}
+}
+
+func queryOptions(conf *config.Config, base ...query.Option) ([]query.Option, error) {
+	options := append(slices.Clone(base), query.WithEndpoints(conf.APIURL, conf.GraphQLURL))
+	if conf.CacheDir != "" {
+		options = append(options, query.WithCache(conf.CacheDir, time.Duration(conf.GraphQLCacheTTLSeconds)*time.Second))
+	}
+	if !conf.UsesApp() {
+		return options, nil
+	}
+	key, err := conf.AppPrivateKeyPEM()
+	if err != nil {
+		return nil, err
+	}
+	source, err := query.NewAppTokenSource(conf.APIURL, conf.AppID, conf.AppInstallationID, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(options, query.WithTokenSource(source)), nil
+}
+
+// statsWindow returns the window preset or the since and until dates of conf,
+// Validate already rejected combinations of both.
+func statsWindow(conf *config.Config) (stats.Window, error) {
+	if conf.Window != "" {
+		return stats.ParseWindow(conf.Window, time.Now())
+	}
+	var since, until time.Time
+	var err error
+	if conf.Since != "" {
+		if since, err = time.Parse(time.DateOnly, conf.Since); err != nil {
+			return stats.Window{}, err
+		}
+	}
+	if conf.Until != "" {
+		if until, err = time.Parse(time.DateOnly, conf.Until); err != nil {
+			return stats.Window{}, err
+		}
+	}
+	return stats.NewWindow(since, until), nil
+}
+
+// recordHistory appends stat to the snapshot history in output, prunes the
+// snapshots no later run needs and returns the change since the snapshot
+// taken deltaDays ago, nil on the first run.
+func recordHistory(output string, stat *stats.Stats, deltaDays int) (*stats.Delta, error) {
+	if deltaDays == 0 {
+		deltaDays = 7
+	}
+	store := history.NewStore(output)
+	now := time.Now()
+	baseline, err := store.Baseline(now.AddDate(0, 0, -deltaDays))
+	if err != nil {
+		return nil, err
+	}
+	if err = store.Append(history.Snapshot{Time: now, Metrics: history.NewMetrics(stat)}); err != nil {
+		return nil, err
+	}
+	if err = store.Prune(now.AddDate(0, 0, -deltaDays)); err != nil {
+		return nil, err
+	}
+	if baseline == nil {
+		return nil, nil
+	}
+	return stats.Diff(baseline.Metrics.Stats(), stat), nil
+}
+
+// saveStat writes every card to output, options apply to all of them. The
+// extra cards of templates are named after their template file.
+func saveStat(conf *config.Config, stat *stats.Stats, output string, templates *render.Templates, options ...render.Option) error {
+	animation := conf.Animation
 	err := os.MkdirAll(output, 0o755)
 	if err != nil {
 		return err
 	}
 
-	overview, err := render.OverviewSVG(animation, stat)
+	overview, err := render.OverviewSVG(animation, stat, options...)
 	if err != nil {
 		return err
 	}
@@ -79,7 +339,7 @@ func saveStat(animation bool, stat *stats.Stats, output string) error {
 		return err
 	}
 
-	languages, err := render.LanguagesSVG(animation, stat)
+	languages, err := render.LanguagesSVG(animation, stat, options...)
 	if err != nil {
 		return err
 	}
@@ -87,29 +347,188 @@ func saveStat(animation bool, stat *stats.Stats, output string) error {
 	if err != nil {
 		return err
 	}
-	return nil
-}
 
-func sendWebhook(conf *config.Config, obj any) error {
-	if conf.WebhookURL == "" {
-		return nil
+	if stat.LineChange != nil || stat.Views != nil {
+		activity, err := render.ActivitySVG(animation, stat, options...)
+		if err != nil {
+			return err
+		}
+		err = activity.WriteToPath(output + "/activity.svg")
+		if err != nil {
+			return err
+		}
 	}
-	data, err := json.MarshalIndent(map[string]any{
-		"status": obj,
-		"config": conf,
-	}, "", "  ")
+
+	if len(stat.Calendar) > 0 {
+		calendarOptions := options
+		if theme, ok := render.Themes[conf.CalendarTheme]; ok {
+			calendarOptions = append(slices.Clip(options), render.CalendarTheme(theme))
+		}
+		calendar, err := render.CalendarSVG(animation, stat, calendarOptions...)
+		if err != nil {
+			return err
+		}
+		err = calendar.WriteToPath(output + "/calendar.svg")
+		if err != nil {
+			return err
+		}
+	}
+
+	topReposOptions := slices.Clip(options)
+	if conf.TopReposBy != "" {
+		topReposOptions = append(topReposOptions, render.TopReposBy(conf.TopReposBy))
+	}
+	if conf.TopReposCount > 0 {
+		topReposOptions = append(topReposOptions, render.TopReposCount(conf.TopReposCount))
+	}
+	topRepos, err := render.TopReposSVG(animation, stat, topReposOptions...)
 	if err != nil {
 		return err
 	}
-	req, err := http.NewRequest("POST", conf.WebhookURL, bytes.NewReader(data))
+	err = topRepos.WriteToPath(output + "/top-repos.svg")
 	if err != nil {
 		return err
 	}
-	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+
+	repoCards := repoCardFiles(stat.Pinned)
+	for name, repo := range repoCards {
+		card, err := render.RepoCardSVG(animation, repo, options...)
+		if err != nil {
+			return err
+		}
+		err = card.WriteToPath(output + "/" + name)
+		if err != nil {
+			return err
+		}
+	}
+	// Keep the previous cards when the pinned repositories failed to load.
+	pinnedFailed := slices.ContainsFunc(stat.Errors, func(e stats.MetricError) bool {
+		return e.Metric == stats.MetricPinned
+	})
+	if !pinnedFailed {
+		if err = removeStaleRepoCards(output, repoCards); err != nil {
+			return err
+		}
+	}
+
+	if stat.Streaks != nil {
+		streak, err := render.StreakSVG(animation, stat, options...)
+		if err != nil {
+			return err
+		}
+		err = streak.WriteToPath(output + "/streak.svg")
+		if err != nil {
+			return err
+		}
+	}
+
+	if stat.Views != nil {
+		traffic, err := render.TrafficSVG(animation, stat, options...)
+		if err != nil {
+			return err
+		}
+		err = traffic.WriteToPath(output + "/traffic.svg")
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, name := range templates.Extra() {
+		card, err := templates.RenderExtra(name, animation, stat)
+		if err != nil {
+			return err
+		}
+		err = card.WriteToPath(output + "/" + name + ".svg")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cardFiles are the names of the cards saveStat writes, besides the pinned
+// repository cards named repo-<name>.svg.
+var cardFiles = []string{"overview", "languages", "activity", "calendar", "top-repos", "streak", "traffic"}
+
+// checkExtraTemplates rejects extra templates whose card would overwrite a
+// built-in one, names are compared case-insensitively for filesystems that
+// ignore case.
+func checkExtraTemplates(templates *render.Templates) error {
+	for _, name := range templates.Extra() {
+		lower := strings.ToLower(name)
+		if slices.Contains(cardFiles, lower) || strings.HasPrefix(lower, "repo-") {
+			return fmt.Errorf("%s.gohtml would overwrite a generated card, rename it", name)
+		}
+	}
+	return nil
+}
+
+// repoCardFiles names the card of each repository repo-<name>.svg, the owner
+// is only added when two repositories share a name.
+func repoCardFiles(repos []*stats.RepoStats) map[string]*stats.RepoStats {
+	names := make(map[string]int, len(repos))
+	for _, repo := range repos {
+		_, name, _ := strings.Cut(repo.Name, "/")
+		names[strings.ToLower(name)]++
+	}
+	files := make(map[string]*stats.RepoStats, len(repos))
+	for _, repo := range repos {
+		owner, name, _ := strings.Cut(repo.Name, "/")
+		if names[strings.ToLower(name)] > 1 {
+			name = owner + "-" + name
+		}
+		files["repo-"+name+".svg"] = repo
+	}
+	return files
+}
+
+// removeStaleRepoCards deletes the repo-<name>.svg files in output that are
+// not among current, left behind by repositories that are no longer pinned.
+func removeStaleRepoCards(output string, current map[string]*stats.RepoStats) error {
+	paths, err := filepath.Glob(filepath.Join(output, "repo-*.svg"))
 	if err != nil {
 		return err
 	}
-	return resp.Body.Close()
+	for _, path := range paths {
+		if _, ok := current[filepath.Base(path)]; ok {
+			continue
+		}
+		if err = os.Remove(path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sendWebhooks delivers stat to every configured target and returns one
+// result per target in config order. A target whose formatter can not be
+// created gets that error as its result, the others are still sent.
+func sendWebhooks(conf *config.Config, stat *stats.Stats) []webhook.Result {
+	if len(conf.Webhooks) == 0 {
+		return nil
+	}
+	results := make([]webhook.Result, len(conf.Webhooks))
+	targets := make([]webhook.Target, 0, len(conf.Webhooks))
+	indexes := make([]int, 0, len(conf.Webhooks))
+	for i, target := range conf.Webhooks {
+		t := webhook.Target{
+			URL:         target.URL,
+			Secret:      target.Secret,
+			Timeout:     time.Duration(target.TimeoutSeconds) * time.Second,
+			MaxRetries:  target.MaxRetries,
+			ContentType: target.ContentType,
+		}
+		formatter, err := webhook.NewFormatter(target.Format, target.Template)
+		if err != nil {
+			results[i] = webhook.Result{Target: t.String(), Err: fmt.Errorf("create formatter: %w", err)}
+			continue
+		}
+		t.Formatter = formatter
+		targets = append(targets, t)
+		indexes = append(indexes, i)
+	}
+	for i, result := range webhook.Send(context.Background(), targets, webhook.NewPayload(stat, conf.Redacted())) {
+		results[indexes[i]] = result
+	}
+	return results
 }
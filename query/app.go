@@ -0,0 +1,165 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// GitHub rejects app JWTs that live longer than ten minutes, the issue
+	// time is backdated to allow for clock drift.
+	appJWTLifetime = 9 * time.Minute
+	appJWTBackdate = time.Minute
+	// Installation tokens last an hour and are refreshed this long before.
+	appTokenRefreshMargin = 5 * time.Minute
+)
+
+// AppTokenSource authenticates as a GitHub App installation. It signs a JWT
+// with the app private key, exchanges it for an installation token and
+// refreshes that token before it expires.
+type AppTokenSource struct {
+	appID          string
+	installationID int64
+	key            *rsa.PrivateKey
+	restURL        string
+	client         *http.Client
+
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func NewAppTokenSource(restURL, appID string, installationID int64, privateKeyPEM []byte) (*AppTokenSource, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+	restURL, _ = ResolveEndpoints(restURL, "")
+	return &AppTokenSource{
+		appID:          appID,
+		installationID: installationID,
+		key:            key,
+		restURL:        restURL,
+		client:         &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// useTransport sends the token exchange through transport, so it follows
+// WithTransport like every other request.
+func (a *AppTokenSource) useTransport(transport http.RoundTripper) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.client = &http.Client{Transport: transport, Timeout: a.client.Timeout}
+}
+
+func (a *AppTokenSource) Token(ctx context.Context) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Now().Add(appTokenRefreshMargin).Before(a.expiresAt) {
+		return a.token, nil
+	}
+	token, expiresAt, err := a.exchange(ctx)
+	if err != nil {
+		return "", fmt.Errorf("github app installation token: %w", err)
+	}
+	a.token, a.expiresAt = token, expiresAt
+	return token, nil
+}
+
+func (a *AppTokenSource) exchange(ctx context.Context) (string, time.Time, error) {
+	jwt, err := a.signJWT(time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", a.restURL, a.installationID)
+	req, err := http.NewRequestWithContext(ctx, "POST", url, nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("Accept", "application/vnd.github+json")
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return "", time.Time{}, ErrBadCredentials
+	}
+	if resp.StatusCode != http.StatusCreated && resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("unexpected status %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	var result struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	if err = json.Unmarshal(body, &result); err != nil {
+		return "", time.Time{}, err
+	}
+	if result.Token == "" {
+		return "", time.Time{}, errors.New("empty token in response")
+	}
+	return result.Token, result.ExpiresAt, nil
+}
+
+func (a *AppTokenSource) signJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{
+		"iat": now.Add(-appJWTBackdate).Unix(),
+		"exp": now.Add(appJWTLifetime).Unix(),
+		"iss": a.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// parsePrivateKey accepts the PKCS#1 keys GitHub hands out as well as PKCS#8.
+func parsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("github app private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parse github app private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("github app private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,316 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WithCache keeps API responses in dir between runs. REST responses are
+// revalidated with If-None-Match and If-Modified-Since, which GitHub does not
+// count against the rate limit when it answers 304. GraphQL responses are
+// reused for graphqlTTL, a zero TTL disables caching them.
+func WithCache(dir string, graphqlTTL time.Duration) Option {
+	return func(q *Queries) {
+		q.cache = &CacheTransport{Dir: dir, GraphQLTTL: graphqlTTL}
+	}
+}
+
+// cacheMaxIdle is how long an entry is kept without being used.
+const cacheMaxIdle = 7 * 24 * time.Hour
+
+type CacheTransport struct {
+	Dir        string
+	GraphQLTTL time.Duration
+	Next       http.RoundTripper
+	// Identity names the account a request is sent as, so entries survive
+	// token rotation. Entries are keyed by the Authorization header when it
+	// is nil.
+	Identity func(req *http.Request) string
+
+	pruneOnce sync.Once
+}
+
+type cacheEntry struct {
+	StoredAt   time.Time   `json:"stored_at"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	t.pruneOnce.Do(t.prune)
+	switch req.Method {
+	case http.MethodGet:
+		return t.revalidate(next, req)
+	case http.MethodPost:
+		if t.GraphQLTTL > 0 {
+			return t.reuse(next, req)
+		}
+	}
+	return next.RoundTrip(req)
+}
+
+// revalidate sends a conditional request when a cached copy exists and serves
+// the cached body when GitHub answers 304 Not Modified.
+func (t *CacheTransport) revalidate(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	path := t.entryPath(req, nil)
+	entry := readCacheEntry(path)
+	if entry != nil {
+		req = req.Clone(req.Context())
+		if etag := entry.Header.Get("ETag"); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Header.Get("Last-Modified"); modified != "" {
+			req.Header.Set("If-Modified-Since", modified)
+		}
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified && entry != nil {
+		_ = resp.Body.Close()
+		cached := entry.response(req)
+		// Keep the fresh rate limit headers for the scheduler.
+		for key, values := range resp.Header {
+			cached.Header[key] = values
+		}
+		return cached, nil
+	}
+	if resp.StatusCode != http.StatusOK || (resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "") {
+		return resp, nil
+	}
+	return t.store(path, req, resp, nil)
+}
+
+// reuse answers GraphQL requests from the cache while the entry is younger
+// than GraphQLTTL.
+func (t *CacheTransport) reuse(next http.RoundTripper, req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	path := t.entryPath(req, body)
+	if entry := readCacheEntry(path); entry != nil && time.Since(entry.StoredAt) < t.GraphQLTTL {
+		return entry.response(req), nil
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusOK {
+		return resp, err
+	}
+	return t.store(path, req, resp, hasNoGraphQLErrors)
+}
+
+// hasNoGraphQLErrors keeps rate limits and transient failures, which GitHub
+// reports with 200 OK, out of the cache.
+func hasNoGraphQLErrors(body []byte) bool {
+	var resp struct {
+		Errors []json.RawMessage `json:"errors"`
+	}
+	return json.Unmarshal(body, &resp) == nil && len(resp.Errors) == 0
+}
+
+// store writes resp to path unless cacheable, when given, rejects its body.
+func (t *CacheTransport) store(path string, req *http.Request, resp *http.Response, cacheable func(body []byte) bool) (*http.Response, error) {
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if cacheable != nil && !cacheable(body) {
+		return resp, nil
+	}
+	entry := &cacheEntry{
+		StoredAt:   time.Now(),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}
+	for _, key := range redactedResponseHeaders {
+		entry.Header.Del(key)
+	}
+	if err = writeCacheEntry(path, entry); err != nil {
+		return nil, fmt.Errorf("write cache: %w", err)
+	}
+	return resp, nil
+}
+
+// entryPath keys an entry by the request and the identity it is sent as, so
+// responses are never shared between different accounts.
+func (t *CacheTransport) entryPath(req *http.Request, body []byte) string {
+	identity := req.Header.Get("Authorization")
+	if t.Identity != nil {
+		identity = t.Identity(req)
+	}
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n%s\n", req.Method, req.URL.String(), identity)
+	hash.Write(body)
+	return filepath.Join(t.Dir, hex.EncodeToString(hash.Sum(nil))[:32]+".json")
+}
+
+// prune removes the entries that were not used for cacheMaxIdle, reading an
+// entry refreshes its modification time.
+func (t *CacheTransport) prune() {
+	entries, err := os.ReadDir(t.Dir)
+	if err != nil {
+		return
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err == nil && info.Mode().IsRegular() && time.Since(info.ModTime()) > cacheMaxIdle {
+			_ = os.Remove(filepath.Join(t.Dir, entry.Name()))
+		}
+	}
+}
+
+// cacheIdentity resolves the account behind the token of req once per token:
+// the installation of an AppTokenSource, the login of the token or, for
+// installation tokens such as GITHUB_TOKEN that can not read GET /user, the
+// repositories of the installation. The Authorization header is used when
+// neither can be resolved.
+func (q *Queries) cacheIdentity(req *http.Request) string {
+	if source, ok := q.tokens.(*AppTokenSource); ok {
+		return fmt.Sprintf("installation %d", source.installationID)
+	}
+	fields := strings.Fields(req.Header.Get("Authorization"))
+	if len(fields) == 0 {
+		return ""
+	}
+	token := fields[len(fields)-1]
+	q.identityMu.Lock()
+	defer q.identityMu.Unlock()
+	if identity, ok := q.identities[token]; ok {
+		return identity
+	}
+	identity, err := q.resolveIdentity(req.Context(), token)
+	if err != nil {
+		log.Printf("Failed to resolve the cache identity, keying the cache by token: %v", err)
+		identity = "token " + token
+	}
+	q.identities[token] = identity
+	return identity
+}
+
+func (q *Queries) resolveIdentity(ctx context.Context, token string) (string, error) {
+	client := &http.Client{Transport: q.transport}
+	get := func(path string, result any) (int, error) {
+		req, err := http.NewRequestWithContext(ctx, "GET", q.restURL+"/"+path, nil)
+		if err != nil {
+			return 0, err
+		}
+		req.Header.Set("Authorization", "token "+token)
+		resp, err := client.Do(req)
+		if err != nil {
+			return 0, err
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		if resp.StatusCode != http.StatusOK {
+			return resp.StatusCode, nil
+		}
+		return resp.StatusCode, json.NewDecoder(resp.Body).Decode(result)
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	status, err := get("user", &user)
+	if err != nil {
+		return "", err
+	}
+	if status == http.StatusOK && user.Login != "" {
+		return "user " + user.Login, nil
+	}
+	if status != http.StatusForbidden {
+		return "", fmt.Errorf("GET /user: unexpected status %d", status)
+	}
+	var installation struct {
+		Repositories []struct {
+			ID int64 `json:"id"`
+		} `json:"repositories"`
+	}
+	status, err = get("installation/repositories?per_page=100", &installation)
+	if err != nil {
+		return "", err
+	}
+	if status != http.StatusOK {
+		return "", fmt.Errorf("GET /installation/repositories: unexpected status %d", status)
+	}
+	ids := make([]string, 0, len(installation.Repositories))
+	for _, repo := range installation.Repositories {
+		ids = append(ids, strconv.FormatInt(repo.ID, 10))
+	}
+	slices.Sort(ids)
+	return "installation repositories " + strings.Join(ids, ","), nil
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)),
+		StatusCode:    e.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.Body)),
+		ContentLength: int64(len(e.Body)),
+		Request:       req,
+	}
+}
+
+func readCacheEntry(path string) *cacheEntry {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil {
+		return nil
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return &entry
+}
+
+func writeCacheEntry(path string, entry *cacheEntry) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".entry-*")
+	if err != nil {
+		return err
+	}
+	if _, err = tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
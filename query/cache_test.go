@@ -0,0 +1,126 @@
+package query
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheSkipsGraphQLErrors(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"login":"bob"}`))
+			return
+		}
+		if calls.Add(1) == 1 {
+			_, _ = w.Write([]byte(`{"errors":[{"type":"INTERNAL","message":"try again"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"viewer":{"login":"bob"}}}`))
+	}))
+	defer server.Close()
+
+	q := NewQueries("ghp_x", WithEndpoints(server.URL, server.URL+"/api/graphql"), WithCache(t.TempDir(), time.Hour))
+	if _, err := q.requestGraphql(context.Background(), "{viewer{login}}", nil); err == nil {
+		t.Fatal("first request should fail")
+	}
+	for i := 0; i < 2; i++ {
+		data, err := q.requestGraphql(context.Background(), "{viewer{login}}", nil)
+		if err != nil {
+			t.Fatalf("request %d: %v", i+2, err)
+		}
+		if string(data) != `{"viewer":{"login":"bob"}}` {
+			t.Errorf("request %d: data = %s", i+2, data)
+		}
+	}
+	if got := calls.Load(); got != 2 {
+		t.Errorf("server called %d times, want 2", got)
+	}
+}
+
+// newIdentityServer answers GraphQL requests and tells the accounts behind the
+// tokens apart: ghp_bob_* tokens belong to bob, ghs_* tokens to an
+// installation that can not read GET /user.
+func newIdentityServer(t *testing.T, graphqlCalls *atomic.Int32) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		switch r.Header.Get("Authorization") {
+		case "token ghp_bob_1", "token ghp_bob_2":
+			_, _ = w.Write([]byte(`{"login":"bob"}`))
+		case "token ghp_alice":
+			_, _ = w.Write([]byte(`{"login":"alice"}`))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	})
+	mux.HandleFunc("GET /api/v3/installation/repositories", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"repositories":[{"id":2},{"id":1}]}`))
+	})
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		graphqlCalls.Add(1)
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCacheKeyedByIdentity(t *testing.T) {
+	var calls atomic.Int32
+	server := newIdentityServer(t, &calls)
+	dir := t.TempDir()
+	request := func(token string) {
+		t.Helper()
+		q := NewQueries(token, WithEndpoints(server.URL, ""), WithCache(dir, time.Hour))
+		if _, err := q.requestGraphql(context.Background(), "{viewer{login}}", nil); err != nil {
+			t.Fatalf("%s: %v", token, err)
+		}
+	}
+	for _, step := range []struct {
+		token string
+		calls int32
+	}{
+		{"ghp_bob_1", 1},
+		{"ghp_bob_2", 1},
+		{"ghp_alice", 2},
+		{"ghs_job_1", 3},
+		{"ghs_job_2", 3},
+	} {
+		request(step.token)
+		if got := calls.Load(); got != step.calls {
+			t.Errorf("after %s: server called %d times, want %d", step.token, got, step.calls)
+		}
+	}
+}
+
+func TestCachePrunesIdleEntries(t *testing.T) {
+	var calls atomic.Int32
+	server := newIdentityServer(t, &calls)
+	dir := t.TempDir()
+	idle := filepath.Join(dir, "idle.json")
+	if err := os.WriteFile(idle, []byte(`{}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-cacheMaxIdle - time.Hour)
+	if err := os.Chtimes(idle, old, old); err != nil {
+		t.Fatal(err)
+	}
+	q := NewQueries("ghp_bob_1", WithEndpoints(server.URL, ""), WithCache(dir, time.Hour))
+	if _, err := q.requestGraphql(context.Background(), "{viewer{login}}", nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(idle); !os.IsNotExist(err) {
+		t.Errorf("idle entry was not pruned: %v", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("cache has %d entries, want 1", len(entries))
+	}
+}
@@ -0,0 +1,195 @@
+package query
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Fixture is one recorded request with every response it received, in order.
+// Only the method, path and body of the request are stored, never its headers,
+// so the Authorization header can not end up in a fixture file.
+type Fixture struct {
+	Method    string            `json:"method"`
+	URL       string            `json:"url"`
+	Body      string            `json:"body,omitempty"`
+	Responses []FixtureResponse `json:"responses"`
+}
+
+type FixtureResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// redactedResponseHeaders are dropped from recorded responses.
+var redactedResponseHeaders = []string{"Set-Cookie", "Authorization", "X-Github-Request-Id"}
+
+// RecordTransport forwards requests to Next, http.DefaultTransport when nil,
+// and appends every response to a fixture file in Dir.
+type RecordTransport struct {
+	Dir  string
+	Next http.RoundTripper
+
+	mu sync.Mutex
+}
+
+func (t *RecordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	next := t.Next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	resp, err := next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	header := resp.Header.Clone()
+	for _, key := range redactedResponseHeaders {
+		header.Del(key)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	path := fixturePath(t.Dir, req, body)
+	fixture, err := readFixture(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fixture = &Fixture{Method: req.Method, URL: req.URL.RequestURI(), Body: string(body)}
+	} else if err != nil {
+		return nil, err
+	}
+	fixture.Responses = append(fixture.Responses, FixtureResponse{
+		StatusCode: resp.StatusCode,
+		Header:     header,
+		Body:       string(redactToken(respBody)),
+	})
+	if err = writeFixture(path, fixture); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// ReplayTransport answers requests from the fixtures in Dir without touching
+// the network. Repeated requests get the recorded responses in order, the last
+// one is repeated once they are used up.
+type ReplayTransport struct {
+	Dir string
+
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	path := fixturePath(t.Dir, req, body)
+	fixture, err := readFixture(path)
+	if err != nil {
+		return nil, fmt.Errorf("no fixture for %s %s: %w", req.Method, req.URL.RequestURI(), err)
+	}
+	if len(fixture.Responses) == 0 {
+		return nil, fmt.Errorf("fixture %s has no responses", path)
+	}
+	t.mu.Lock()
+	if t.calls == nil {
+		t.calls = make(map[string]int)
+	}
+	index := min(t.calls[path], len(fixture.Responses)-1)
+	t.calls[path]++
+	t.mu.Unlock()
+
+	recorded := fixture.Responses[index]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader([]byte(recorded.Body))),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// redactToken replaces the installation token GitHub returns when an app
+// token is exchanged, replays refresh it like an expired one.
+func redactToken(body []byte) []byte {
+	var fields map[string]json.RawMessage
+	if json.Unmarshal(body, &fields) != nil {
+		return body
+	}
+	if _, ok := fields["token"]; !ok {
+		return body
+	}
+	fields["token"] = json.RawMessage(`"ghs_redacted"`)
+	redacted, err := json.Marshal(fields)
+	if err != nil {
+		return body
+	}
+	return redacted
+}
+
+// fixturePath keys a request by method, path, query and body. The host is left
+// out so fixtures recorded against github.com replay against any endpoint.
+func fixturePath(dir string, req *http.Request, body []byte) string {
+	hash := sha256.New()
+	_, _ = fmt.Fprintf(hash, "%s %s\n", req.Method, req.URL.RequestURI())
+	hash.Write(body)
+	return filepath.Join(dir, hex.EncodeToString(hash.Sum(nil))[:16]+".json")
+}
+
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil {
+		return nil, nil
+	}
+	body, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+func readFixture(path string) (*Fixture, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var fixture Fixture
+	if err = json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &fixture, nil
+}
+
+func writeFixture(path string, fixture *Fixture) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(fixture, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
@@ -0,0 +1,52 @@
+package query
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newInstallationServer(t)
+	dir := t.TempDir()
+	source, err := NewAppTokenSource(server.URL, "1", 42, testPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorder := NewQueries("", WithEndpoints(server.URL, ""), WithTransport(&RecordTransport{Dir: dir}), WithTokenSource(source))
+	if _, err = recorder.TokenInfo(context.Background()); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(paths) != 2 {
+		t.Fatalf("recorded %d fixtures, want 2", len(paths))
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(string(data), "ghs_installation") || strings.Contains(string(data), "Authorization") {
+			t.Errorf("%s leaks credentials:\n%s", path, data)
+		}
+	}
+
+	source, err = NewAppTokenSource("https://github.example.com", "1", 42, testPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayer := NewQueries("", WithEndpoints("https://github.example.com", ""), WithTransport(&ReplayTransport{Dir: dir}), WithTokenSource(source))
+	info, err := replayer.TokenInfo(context.Background())
+	if err != nil {
+		t.Fatalf("replay: %v", err)
+	}
+	if info.RateLimit.Remaining != 4990 {
+		t.Errorf("replayed RateLimit = %+v, want 4990 remaining", info.RateLimit)
+	}
+	if _, err = replayer.requestRest(context.Background(), "repos/bob/missing", nil); err == nil {
+		t.Error("replay answered a request without fixture")
+	}
+}
@@ -0,0 +1,138 @@
+package query
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGraphQLVariables checks that logins and cursors reach GitHub only
+// through the variables of a request, never inside the query text.
+func TestGraphQLVariables(t *testing.T) {
+	const login = `bo"b}`
+	const cursor = `Y3"}{`
+	var bodies []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		_, _ = w.Write([]byte(`{"data":{}}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	q := NewQueries("ghp_x", WithEndpoints(server.URL, ""))
+	ctx := context.Background()
+	from := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		call      func() error
+		query     string
+		variables string
+	}{
+		{
+			name: "repositories",
+			call: func() error {
+				_, err := q.Repositories(ctx, login, cursor)
+				return err
+			},
+			query:     repositoriesQuery,
+			variables: `{"login":"bo\"b}","after":"Y3\"}{"}`,
+		},
+		{
+			name: "first page of repositories",
+			call: func() error {
+				_, err := q.Repositories(ctx, login, "")
+				return err
+			},
+			query:     repositoriesQuery,
+			variables: `{"login":"bo\"b}","after":null}`,
+		},
+		{
+			name: "repositories contributed to",
+			call: func() error {
+				_, err := q.RepositoriesContributedTo(ctx, login, cursor)
+				return err
+			},
+			query:     repositoriesContributedToQuery,
+			variables: `{"login":"bo\"b}","after":"Y3\"}{"}`,
+		},
+		{
+			name: "repository languages",
+			call: func() error {
+				_, err := q.RepositoryLanguages(ctx, login+`/re"po`, cursor)
+				return err
+			},
+			query:     repositoryLanguagesQuery,
+			variables: `{"owner":"bo\"b}","name":"re\"po","after":"Y3\"}{"}`,
+		},
+		{
+			name: "pinned items",
+			call: func() error {
+				_, err := q.PinnedItems(ctx, login)
+				return err
+			},
+			query:     pinnedItemsQuery,
+			variables: `{"login":"bo\"b}"}`,
+		},
+		{
+			name: "contributions",
+			call: func() error {
+				_, err := q.ContributionsCollection(ctx, login)
+				return err
+			},
+			query:     contributionsCollectionQuery,
+			variables: `{"login":"bo\"b}"}`,
+		},
+		{
+			name: "contributions window",
+			call: func() error {
+				_, err := q.ContributionsWindow(ctx, login, from, from.AddDate(0, 3, 0))
+				return err
+			},
+			query:     contributionsWindowQuery,
+			variables: `{"login":"bo\"b}","from":"2026-01-01T00:00:00Z","to":"2026-04-01T00:00:00Z"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bodies = nil
+			if err := tt.call(); err != nil {
+				t.Fatal(err)
+			}
+			query, _ := json.Marshal(tt.query)
+			want := `{"query":` + string(query) + `,"variables":` + tt.variables + `}`
+			if len(bodies) != 1 || bodies[0] != want {
+				t.Errorf("request bodies = %q, want %q", bodies, want)
+			}
+		})
+	}
+
+	t.Run("contribution years", func(t *testing.T) {
+		bodies = nil
+		if _, err := q.AllContribYears(ctx, login, []int{2025, 2026}); err != nil {
+			t.Fatal(err)
+		}
+		if len(bodies) != 1 {
+			t.Fatalf("%d requests, want 1", len(bodies))
+		}
+		var request struct {
+			Query     string            `json:"query"`
+			Variables map[string]string `json:"variables"`
+		}
+		if err := json.Unmarshal([]byte(bodies[0]), &request); err != nil {
+			t.Fatal(err)
+		}
+		if strings.Contains(request.Query, login) || strings.Contains(request.Query, `"`) {
+			t.Errorf("query text contains user input: %s", request.Query)
+		}
+		if request.Variables["login"] != login || request.Variables["from2025"] != "2025-01-01T00:00:00Z" || request.Variables["to2026"] != "2027-01-01T00:00:00Z" {
+			t.Errorf("variables = %v", request.Variables)
+		}
+	})
+}
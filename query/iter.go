@@ -0,0 +1,55 @@
+package query
+
+import (
+	"context"
+	"iter"
+)
+
+type repositoriesPager func(ctx context.Context, login, after string) (*RepositoriesPage, error)
+
+// AllRepositories walks the repositories owned by login page by page. The
+// next page is only requested once the previous one has been consumed, so
+// breaking out of the loop stops fetching.
+func (q *Queries) AllRepositories(ctx context.Context, login string) iter.Seq2[Repository, error] {
+	return paginateRepositories(ctx, login, q.Repositories)
+}
+
+// AllRepositoriesContributedTo walks the repositories login contributed to,
+// see AllRepositories.
+func (q *Queries) AllRepositoriesContributedTo(ctx context.Context, login string) iter.Seq2[Repository, error] {
+	return paginateRepositories(ctx, login, q.RepositoriesContributedTo)
+}
+
+// paginateRepositories yields every repository of the connection. A failed
+// page or a cancelled context is yielded once as an error and ends the
+// sequence, the rest of a page is dropped when the context is cancelled
+// while it is consumed.
+func paginateRepositories(ctx context.Context, login string, pager repositoriesPager) iter.Seq2[Repository, error] {
+	return func(yield func(Repository, error) bool) {
+		after := ""
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(Repository{}, err)
+				return
+			}
+			page, err := pager(ctx, login, after)
+			if err != nil {
+				yield(Repository{}, err)
+				return
+			}
+			for _, repo := range page.Nodes {
+				if err = ctx.Err(); err != nil {
+					yield(Repository{}, err)
+					return
+				}
+				if !yield(repo, nil) {
+					return
+				}
+			}
+			if !page.PageInfo.HasNextPage {
+				return
+			}
+			after = page.PageInfo.EndCursor
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// pagesOf serves pages of two repositories each and records the cursors it
+// was asked for.
+type pagesOf struct {
+	pages   int
+	cursors []string
+}
+
+func (p *pagesOf) page(ctx context.Context, login, after string) (*RepositoriesPage, error) {
+	p.cursors = append(p.cursors, after)
+	n := len(p.cursors)
+	page := &RepositoriesPage{PageInfo: PageInfo{HasNextPage: n < p.pages, EndCursor: fmt.Sprintf("cursor%d", n)}}
+	for i := range 2 {
+		page.Nodes = append(page.Nodes, Repository{NameWithOwner: fmt.Sprintf("%s/repo%d-%d", login, n, i)})
+	}
+	return page, nil
+}
+
+// checkGoroutines fails when the test left more goroutines running than it
+// found.
+func checkGoroutines(t *testing.T) {
+	t.Helper()
+	before := runtime.NumGoroutine()
+	t.Cleanup(func() {
+		for range 50 {
+			if runtime.NumGoroutine() <= before {
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Errorf("%d goroutines leaked", runtime.NumGoroutine()-before)
+	})
+}
+
+func TestPaginateRepositories(t *testing.T) {
+	checkGoroutines(t)
+	pager := &pagesOf{pages: 3}
+	var names []string
+	for repo, err := range paginateRepositories(context.Background(), "bob", pager.page) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, repo.NameWithOwner)
+	}
+	if len(names) != 6 || fmt.Sprint(pager.cursors) != "[ cursor1 cursor2]" {
+		t.Errorf("repositories = %v with cursors %q, want 6 over 3 pages", names, pager.cursors)
+	}
+}
+
+func TestPaginateRepositoriesBreak(t *testing.T) {
+	checkGoroutines(t)
+	pager := &pagesOf{pages: 3}
+	var names []string
+	for repo, err := range paginateRepositories(context.Background(), "bob", pager.page) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, repo.NameWithOwner)
+		if len(names) == 3 {
+			break
+		}
+	}
+	// The third repository is the first of the second page, no third page
+	// is requested.
+	if len(pager.cursors) != 2 {
+		t.Errorf("requested %d pages after breaking on the second, want 2", len(pager.cursors))
+	}
+}
+
+func TestPaginateRepositoriesCancel(t *testing.T) {
+	checkGoroutines(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pager := &pagesOf{pages: 3}
+	var names []string
+	var errs []error
+	for repo, err := range paginateRepositories(ctx, "bob", pager.page) {
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		names = append(names, repo.NameWithOwner)
+		if len(names) == 3 {
+			// Cancelled in the middle of the second page.
+			cancel()
+		}
+	}
+	if len(pager.cursors) != 2 {
+		t.Errorf("requested %d pages, want none after the cancellation", len(pager.cursors))
+	}
+	if len(names) != 3 {
+		t.Errorf("repositories = %v, want the rest of the page dropped", names)
+	}
+	if len(errs) != 1 || !errors.Is(errs[0], context.Canceled) {
+		t.Errorf("errors = %v, want one context.Canceled", errs)
+	}
+}
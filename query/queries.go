@@ -9,46 +9,135 @@ import (
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
+	"sync"
 	"time"
 )
 
 var (
 	ErrAcceptButNotReady = fmt.Errorf("request accepted but not ready")
 	ErrTooManyRequests   = fmt.Errorf("too many requests")
+	ErrBadCredentials    = fmt.Errorf("bad credentials")
+)
+
+const (
+	DefaultRESTURL    = "https://api.github.com"
+	DefaultGraphQLURL = "https://api.github.com/graphql"
 )
 
 type Queries struct {
-	accessToken string
-	client      *http.Client
+	tokens     TokenSource
+	restURL    string
+	graphqlURL string
+	transport  http.RoundTripper
+	cache      *CacheTransport
+	client     *http.Client
+	scheduler  *scheduler
+
+	identityMu sync.Mutex
+	identities map[string]string
+}
+
+type Option func(*Queries)
+
+func NewQueries(accessToken string, options ...Option) *Queries {
+	q := &Queries{
+		tokens:     StaticToken(accessToken),
+		restURL:    DefaultRESTURL,
+		graphqlURL: DefaultGraphQLURL,
+		scheduler:  newScheduler(),
+	}
+	for _, option := range options {
+		option(q)
+	}
+	if source, ok := q.tokens.(*AppTokenSource); ok && q.transport != nil {
+		source.useTransport(q.transport)
+	}
+	transport := q.transport
+	if q.cache != nil {
+		q.cache.Next = transport
+		q.cache.Identity = q.cacheIdentity
+		q.identities = make(map[string]string)
+		transport = q.cache
+	}
+	q.client = &http.Client{Transport: transport}
+	return q
+}
+
+// WithEndpoints points the client at a GitHub Enterprise Server instance.
+func WithEndpoints(restURL, graphqlURL string) Option {
+	return func(q *Queries) {
+		q.restURL, q.graphqlURL = ResolveEndpoints(restURL, graphqlURL)
+	}
+}
+
+// ResolveEndpoints expands a bare GHES host into its /api/v3 and /api/graphql endpoints.
+func ResolveEndpoints(restURL, graphqlURL string) (string, string) {
+	restURL = strings.TrimRight(strings.TrimSpace(restURL), "/")
+	graphqlURL = strings.TrimRight(strings.TrimSpace(graphqlURL), "/")
+	if restURL == "" {
+		restURL = DefaultRESTURL
+	}
+	isEnterprise := restURL != DefaultRESTURL
+	if u, err := url.Parse(restURL); err == nil && isEnterprise && (u.Path == "" || u.Path == "/") {
+		restURL += "/api/v3"
+	}
+	if graphqlURL == "" {
+		switch {
+		case !isEnterprise:
+			graphqlURL = DefaultGraphQLURL
+		case strings.HasSuffix(restURL, "/api/v3"):
+			graphqlURL = strings.TrimSuffix(restURL, "/v3") + "/graphql"
+		default:
+			graphqlURL = restURL + "/graphql"
+		}
+	}
+	return restURL, graphqlURL
+}
+
+// WithTransport replaces the HTTP transport used for every API request.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(q *Queries) {
+		q.transport = transport
+	}
 }
 
-func NewQueries(accessToken string) *Queries {
-	return &Queries{
-		accessToken: accessToken,
-		client:      &http.Client{},
+// WithTokenSource replaces the static access token, e.g. with an
+// AppTokenSource that refreshes installation tokens.
+func WithTokenSource(tokens TokenSource) Option {
+	return func(q *Queries) {
+		q.tokens = tokens
 	}
 }
 
 func (q *Queries) IsValid() bool {
-	if q.accessToken == "" {
+	if token, err := q.tokens.Token(context.Background()); err != nil || token == "" {
 		return false
 	}
 	_, err := q.requestRest(context.Background(), "user", nil)
 	return err == nil
 }
 
-func (q *Queries) requestGraphql(ctx context.Context, query string) (json.RawMessage, error) {
-	reqBody, err := json.Marshal(graphQLRequest{Query: query})
+func (q *Queries) requestGraphql(ctx context.Context, query string, variables any) (json.RawMessage, error) {
+	reqBody, err := json.Marshal(graphQLRequest{Query: query, Variables: variables})
 	if err != nil {
 		return nil, err
 	}
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.github.com/graphql", bytes.NewReader(reqBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", q.graphqlURL, bytes.NewReader(reqBody))
 	if err != nil {
 		return nil, err
 	}
-	req.Header.Set("Authorization", "Bearer "+q.accessToken)
 	req.Header.Set("Content-Type", "application/json")
+	if err = q.scheduler.wait(ctx, graphqlResource); err != nil {
+		return nil, err
+	}
+	// The wait can outlast an installation token, so it is read afterwards.
+	token, err := q.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
 	resp, err := q.client.Do(req)
 	if err != nil {
 		return nil, err
@@ -61,25 +150,37 @@ func (q *Queries) requestGraphql(ctx context.Context, query string) (json.RawMes
 	if err != nil {
 		return nil, err
 	}
+	if q.scheduler.throttled(graphqlResource, resp, body) {
+		return nil, ErrTooManyRequests
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		return nil, ErrBadCredentials
+	}
 	var graphqlResp graphQLResponse
 	err = json.Unmarshal(body, &graphqlResp)
 	if err != nil {
 		return nil, err
 	}
 	if len(graphqlResp.Errors) > 0 {
+		if graphqlResp.Errors[0].Type == "RATE_LIMITED" {
+			return nil, ErrTooManyRequests
+		}
 		return nil, fmt.Errorf("graphql error: %s", graphqlResp.Errors[0].Message)
 	}
 	return graphqlResp.Data, nil
 }
 
 func (q *Queries) requestRest(ctx context.Context, path string, params map[string]string) (json.RawMessage, error) {
-	baseURL := fmt.Sprintf("https://api.github.com/%s", strings.TrimLeft(path, "/"))
+	_, body, err := q.doRest(ctx, path, params)
+	return body, err
+}
+
+func (q *Queries) doRest(ctx context.Context, path string, params map[string]string) (*http.Response, []byte, error) {
+	baseURL := fmt.Sprintf("%s/%s", q.restURL, strings.TrimLeft(path, "/"))
 	req, err := http.NewRequestWithContext(ctx, "GET", baseURL, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	req.Header.Set("Authorization", fmt.Sprintf("token %s", q.accessToken))
-
 	if params != nil {
 		query := req.URL.Query()
 		for key, value := range params {
@@ -88,74 +189,198 @@ func (q *Queries) requestRest(ctx context.Context, path string, params map[strin
 		req.URL.RawQuery = query.Encode()
 	}
 
+	if err = q.scheduler.wait(ctx, restResource); err != nil {
+		return nil, nil, err
+	}
+	token, err := q.tokens.Token(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
 	resp, err := q.client.Do(req)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	if resp.StatusCode == http.StatusAccepted {
-		return nil, ErrAcceptButNotReady
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
 	}
-	if resp.StatusCode == http.StatusTooManyRequests {
-		return nil, ErrTooManyRequests
+	if q.scheduler.throttled(restResource, resp, body) {
+		return resp, nil, ErrTooManyRequests
 	}
-	return io.ReadAll(resp.Body)
-}
-
-func (q *Queries) formatAfterCursor(after string) string {
-	if after == "" {
-		return "null"
+	if resp.StatusCode == http.StatusUnauthorized {
+		return resp, nil, ErrBadCredentials
 	}
-	return fmt.Sprintf(`"%s"`, after)
+	if resp.StatusCode == http.StatusAccepted {
+		return resp, nil, ErrAcceptButNotReady
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return resp, nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp, body, nil
 }
 
-func (q *Queries) repositoriesQuery(query, login string) string {
-	return fmt.Sprintf(`
-query {
-  user(login: "%s") {
-    repositories: %s {
+const repositoryConnectionFragment = `
+fragment RepositoryConnectionFields on RepositoryConnection {
+  pageInfo {
+    hasNextPage
+    endCursor
+  }
+  nodes {
+    nameWithOwner
+    description
+    primaryLanguage {
+      name
+      color
+    }
+    stargazers {
+      totalCount
+    }
+    forkCount
+    isFork
+    isArchived
+    isPrivate
+    viewerPermission
+    languages(first: 10, orderBy: {field: SIZE, direction: DESC}) {
       pageInfo {
         hasNextPage
         endCursor
       }
-      nodes {
-        nameWithOwner
-        stargazers {
-          totalCount
+      edges {
+        size
+        node {
+          name
+          color
         }
-        forkCount
-        isFork
-        isArchived
-        isPrivate
-        languages(first: 10, orderBy: {field: SIZE, direction: DESC}) {
-          edges {
-            size
-            node {
-              name
-              color
-            }
+      }
+    }
+  }
+}`
+
+const repositoriesQuery = `
+query($login: String!, $after: String) {
+  user(login: $login) {
+    repositories: repositories(
+      first: 100,
+      orderBy: {
+        field: UPDATED_AT,
+        direction: DESC
+      },
+      isFork: false,
+      after: $after
+    ) {
+      ...RepositoryConnectionFields
+    }
+  }
+}` + repositoryConnectionFragment
+
+const repositoriesContributedToQuery = `
+query($login: String!, $after: String) {
+  user(login: $login) {
+    repositories: repositoriesContributedTo(
+      first: 100,
+      includeUserRepositories: false,
+      orderBy: {
+        field: UPDATED_AT,
+        direction: DESC
+      },
+      contributionTypes: [
+        COMMIT,
+        PULL_REQUEST,
+        REPOSITORY,
+        PULL_REQUEST_REVIEW
+      ]
+      after: $after
+    ) {
+      ...RepositoryConnectionFields
+    }
+  }
+}` + repositoryConnectionFragment
+
+const contributionsCollectionQuery = `
+query($login: String!) {
+  user(login: $login) {
+    contributionsCollection {
+      contributionYears
+      totalCommitContributions
+      totalIssueContributions
+      totalPullRequestContributions
+      totalPullRequestReviewContributions
+    }
+  }
+}`
+
+// contributionCalendarFields selects the total and the daily breakdown of a
+// contributionCalendar.
+const contributionCalendarFields = `
+        totalContributions
+        weeks {
+          contributionDays {
+            date
+            contributionCount
+            color
+          }
+        }`
+
+const contributionsWindowQuery = `
+query($login: String!, $from: DateTime!, $to: DateTime!) {
+  user(login: $login) {
+    contributionsCollection(from: $from, to: $to) {
+      contributionYears
+      contributionCalendar {` + contributionCalendarFields + `
+      }
+      totalCommitContributions
+      totalIssueContributions
+      totalPullRequestContributions
+      totalPullRequestReviewContributions
+    }
+  }
+}`
+
+const pinnedItemsQuery = `
+query($login: String!) {
+  user(login: $login) {
+    pinnedItems(first: 6, types: [REPOSITORY]) {
+      nodes {
+        ... on Repository {
+          nameWithOwner
+          description
+          stargazerCount
+          forkCount
+          primaryLanguage {
+            name
+            color
           }
         }
       }
     }
   }
-}`, login, strings.TrimSpace(query))
-}
+}`
+
+const repositoryLanguagesQuery = `
+query($owner: String!, $name: String!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    languages(first: 100, orderBy: {field: SIZE, direction: DESC}, after: $after) {
+      pageInfo {
+        hasNextPage
+        endCursor
+      }
+      edges {
+        size
+        node {
+          name
+          color
+        }
+      }
+    }
+  }
+}`
 
 func (q *Queries) Repositories(ctx context.Context, login, after string) (*RepositoriesPage, error) {
-	query := q.repositoriesQuery(fmt.Sprintf(`
-	repositories(
-        first: 100,
-        orderBy: {
-            field: UPDATED_AT,
-            direction: DESC
-        },
-        isFork: false,
-        after: %s
-    )`, q.formatAfterCursor(after)), login)
-	data, err := sendQuery[Repositories](ctx, q, query)
+	data, err := sendQuery[Repositories](ctx, q, repositoriesQuery, newRepositoriesVariables(login, after))
 	if err != nil {
 		return nil, err
 	}
@@ -163,62 +388,77 @@ func (q *Queries) Repositories(ctx context.Context, login, after string) (*Repos
 }
 
 func (q *Queries) RepositoriesContributedTo(ctx context.Context, login, after string) (*RepositoriesPage, error) {
-	query := q.repositoriesQuery(fmt.Sprintf(`
-	repositoriesContributedTo(
-        first: 100,
-        includeUserRepositories: false,
-        orderBy: {
-            field: UPDATED_AT,
-            direction: DESC
-        },
-        contributionTypes: [
-            COMMIT,
-            PULL_REQUEST,
-            REPOSITORY,
-            PULL_REQUEST_REVIEW
-        ]
-        after: %s
-    )`, q.formatAfterCursor(after)), login)
-	data, err := sendQuery[Repositories](ctx, q, query)
+	data, err := sendQuery[Repositories](ctx, q, repositoriesContributedToQuery, newRepositoriesVariables(login, after))
 	if err != nil {
 		return nil, err
 	}
 	return &data.Repositories, nil
 }
 
+// RepositoryLanguages returns the languages of nameWithOwner after the given
+// cursor, used to fetch the tail cut off by the repositories queries.
+func (q *Queries) RepositoryLanguages(ctx context.Context, nameWithOwner, after string) (*LanguagesPage, error) {
+	owner, name, _ := strings.Cut(nameWithOwner, "/")
+	variables := repositoryLanguagesVariables{Owner: owner, Name: name}
+	if after != "" {
+		variables.After = &after
+	}
+	data, err := sendGraphql[struct {
+		Repository struct {
+			Languages LanguagesPage `json:"languages"`
+		} `json:"repository"`
+	}](ctx, q, repositoryLanguagesQuery, variables)
+	if err != nil {
+		return nil, err
+	}
+	return &data.Repository.Languages, nil
+}
+
+// PinnedItems returns the repositories pinned to the profile of login, in
+// the order they are shown.
+func (q *Queries) PinnedItems(ctx context.Context, login string) ([]PinnedRepository, error) {
+	data, err := sendQuery[PinnedItems](ctx, q, pinnedItemsQuery, loginVariables{Login: login})
+	if err != nil {
+		return nil, err
+	}
+	return data.PinnedItems.Nodes, nil
+}
+
 func (q *Queries) ContributionsCollection(ctx context.Context, login string) (*ContributionsCollection, error) {
-	query := fmt.Sprintf(`
-query {
-  user(login: "%s") {
-    contributionsCollection {
-      contributionYears
-      totalCommitContributions
-      totalIssueContributions
-      totalPullRequestContributions
-      totalPullRequestReviewContributions
-    }
-  }
-}`, login)
-	return sendQuery[ContributionsCollection](ctx, q, query)
+	return sendQuery[ContributionsCollection](ctx, q, contributionsCollectionQuery, loginVariables{Login: login})
+}
+
+// ContributionsWindow returns the contributions between from and to, GitHub
+// rejects windows longer than one year.
+func (q *Queries) ContributionsWindow(ctx context.Context, login string, from, to time.Time) (*ContributionsCollection, error) {
+	return sendQuery[ContributionsCollection](ctx, q, contributionsWindowQuery, windowVariables{
+		Login: login,
+		From:  from.UTC().Format(time.RFC3339),
+		To:    to.UTC().Format(time.RFC3339),
+	})
 }
 
 func (q *Queries) AllContribYears(ctx context.Context, login string, years []int) (AllContribYears, error) {
-	var byYears string
+	// Aliases and variable names are derived from the integer years only, every
+	// user supplied value travels through the variables map.
+	variables := map[string]any{"login": login}
+	var definitions, byYears string
 	for _, year := range years {
+		variables[fmt.Sprintf("from%d", year)] = fmt.Sprintf("%d-01-01T00:00:00Z", year)
+		variables[fmt.Sprintf("to%d", year)] = fmt.Sprintf("%d-01-01T00:00:00Z", year+1)
+		definitions += fmt.Sprintf(", $from%d: DateTime!, $to%d: DateTime!", year, year)
 		byYears += fmt.Sprintf(`
-    	year%d: contributionsCollection(from: "%d-01-01T00:00:00Z",to: "%d-01-01T00:00:00Z") {
-    	  contributionCalendar {
-    	    totalContributions
-    	  }
-    	}`, year, year, year+1)
+    year%d: contributionsCollection(from: $from%d, to: $to%d) {
+      contributionCalendar {%s
+      }
+    }`, year, year, year, contributionCalendarFields)
 	}
 	query := fmt.Sprintf(`
-query {
-  user(login: "%s") {
-    %s
+query($login: String!%s) {
+  user(login: $login) {%s
   }
-}`, login, byYears)
-	data, err := sendQuery[AllContribYears](ctx, q, query)
+}`, definitions, byYears)
+	data, err := sendQuery[AllContribYears](ctx, q, query, variables)
 	if err != nil {
 		return nil, err
 	}
@@ -229,42 +469,74 @@ func (q *Queries) RepoTraffic(ctx context.Context, repo string) (*RepoTraffic, e
 	return sendRequest[RepoTraffic](ctx, q, fmt.Sprintf("/repos/%s/traffic/views", repo), 1, nil)
 }
 
+func (q *Queries) RepoClones(ctx context.Context, repo string) (*RepoClones, error) {
+	return sendRequest[RepoClones](ctx, q, fmt.Sprintf("/repos/%s/traffic/clones", repo), 1, nil)
+}
+
+func (q *Queries) RepoReferrers(ctx context.Context, repo string) (*[]RepoReferrer, error) {
+	return sendRequest[[]RepoReferrer](ctx, q, fmt.Sprintf("/repos/%s/traffic/popular/referrers", repo), 1, nil)
+}
+
+func (q *Queries) RepoPopularPaths(ctx context.Context, repo string) (*[]RepoPopularPath, error) {
+	return sendRequest[[]RepoPopularPath](ctx, q, fmt.Sprintf("/repos/%s/traffic/popular/paths", repo), 1, nil)
+}
+
 func (q *Queries) RepoContributors(ctx context.Context, repo string) (*[]RepoContributor, error) {
 	return sendRequest[[]RepoContributor](ctx, q, fmt.Sprintf("/repos/%s/stats/contributors", repo), 60, nil)
 }
 
-func sendQuery[T any](ctx context.Context, client *Queries, query string) (*T, error) {
-	data, err := client.requestGraphql(ctx, query)
+func sendQuery[T any, V any](ctx context.Context, client *Queries, query string, variables V) (*T, error) {
+	result, err := sendGraphql[struct {
+		User T `json:"user"`
+	}](ctx, client, query, variables)
 	if err != nil {
 		return nil, err
 	}
-	var result struct {
-		User T `json:"user"`
+	return &result.User, nil
+}
+
+func sendGraphql[T any, V any](ctx context.Context, client *Queries, query string, variables V) (*T, error) {
+	data, err := client.requestGraphql(ctx, query, variables)
+	for i := 0; i < maxThrottledRetries && errors.Is(err, ErrTooManyRequests); i++ {
+		log.Printf("Too many requests, retrying when the rate limit allows")
+		data, err = client.requestGraphql(ctx, query, variables)
+	}
+	if err != nil {
+		return nil, err
 	}
+	var result T
 	err = json.Unmarshal(data, &result)
 	if err != nil {
 		return nil, err
 	}
-	return &result.User, nil
+	return &result, nil
 }
 
 func sendRequest[T any](ctx context.Context, client *Queries, path string, maxTries int, params map[string]string) (*T, error) {
-	for i := 0; i < maxTries; i++ {
+	throttled := 0
+	for i := 0; i < maxTries; {
 		data, err := client.requestRest(ctx, path, params)
 		if errors.Is(err, ErrAcceptButNotReady) {
 			log.Printf("Request accepted but not ready, retrying in 1 second")
-			time.Sleep(time.Second * 1)
+			if e := sleepContext(ctx, time.Second); e != nil {
+				return nil, e
+			}
+			i++
 			continue
 		}
-		if errors.Is(err, ErrTooManyRequests) {
-			log.Printf("Too many requests, retrying in 2 second")
-			time.Sleep(time.Second * 2)
+		if errors.Is(err, ErrTooManyRequests) && throttled < maxThrottledRetries {
+			log.Printf("Too many requests, retrying when the rate limit allows")
+			throttled++
 			continue
 		}
 		if err != nil {
 			return nil, err
 		}
 		var result T
+		if len(bytes.TrimSpace(data)) == 0 {
+			// 204 No Content, e.g. the contributors of an empty repository.
+			return &result, nil
+		}
 		err = json.Unmarshal(data, &result)
 		if err != nil {
 			return nil, err
@@ -276,42 +548,93 @@ func sendRequest[T any](ctx context.Context, client *Queries, path string, maxTr
 
 type (
 	graphQLRequest struct {
-		Query string `json:"query"`
+		Query     string `json:"query"`
+		Variables any    `json:"variables,omitempty"`
 	}
 	graphQLResponse struct {
 		Data   json.RawMessage `json:"data,omitempty"`
 		Errors []struct {
+			Type    string `json:"type"`
 			Message string `json:"message"`
 		} `json:"errors,omitempty"`
 	}
 )
 
+type (
+	loginVariables struct {
+		Login string `json:"login"`
+	}
+	windowVariables struct {
+		Login string `json:"login"`
+		From  string `json:"from"`
+		To    string `json:"to"`
+	}
+	repositoriesVariables struct {
+		Login string  `json:"login"`
+		After *string `json:"after"`
+	}
+	repositoryLanguagesVariables struct {
+		Owner string  `json:"owner"`
+		Name  string  `json:"name"`
+		After *string `json:"after"`
+	}
+)
+
+func newRepositoriesVariables(login, after string) repositoriesVariables {
+	variables := repositoriesVariables{Login: login}
+	if after != "" {
+		variables.After = &after
+	}
+	return variables
+}
+
 type (
 	Repository struct {
-		NameWithOwner string `json:"nameWithOwner"`
-		Stargazers    struct {
+		NameWithOwner   string    `json:"nameWithOwner"`
+		Description     string    `json:"description"`
+		PrimaryLanguage *Language `json:"primaryLanguage"`
+		Stargazers      struct {
 			TotalCount int `json:"totalCount"`
 		} `json:"stargazers"`
 		ForkCount  int  `json:"forkCount"`
 		IsFork     bool `json:"isFork"`
 		IsArchived bool `json:"isArchived"`
 		IsPrivate  bool `json:"isPrivate"`
-		Languages  struct {
-			Edges []struct {
-				Size int `json:"size"`
-				Node struct {
-					Name  string `json:"name"`
-					Color string `json:"color"`
-				} `json:"node"`
-			} `json:"edges"`
-		} `json:"languages"`
+		// ViewerPermission is ADMIN, MAINTAIN, WRITE, TRIAGE or READ.
+		ViewerPermission string        `json:"viewerPermission"`
+		Languages        LanguagesPage `json:"languages"`
+	}
+	PinnedRepository struct {
+		NameWithOwner   string    `json:"nameWithOwner"`
+		Description     string    `json:"description"`
+		StargazerCount  int       `json:"stargazerCount"`
+		ForkCount       int       `json:"forkCount"`
+		PrimaryLanguage *Language `json:"primaryLanguage"`
+	}
+	PinnedItems struct {
+		PinnedItems struct {
+			Nodes []PinnedRepository `json:"nodes"`
+		} `json:"pinnedItems"`
+	}
+	PageInfo struct {
+		HasNextPage bool   `json:"hasNextPage"`
+		EndCursor   string `json:"endCursor"`
+	}
+	Language struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
+	}
+	LanguageEdge struct {
+		Size int      `json:"size"`
+		Node Language `json:"node"`
+	}
+	LanguagesPage struct {
+		PageInfo PageInfo       `json:"pageInfo"`
+		Edges    []LanguageEdge `json:"edges"`
 	}
 	RepositoriesPage struct {
-		PageInfo struct {
-			HasNextPage bool   `json:"hasNextPage"`
-			EndCursor   string `json:"endCursor"`
-		} `json:"pageInfo"`
-		Nodes []Repository `json:"nodes"`
+		PageInfo PageInfo     `json:"pageInfo"`
+		Nodes    []Repository `json:"nodes"`
 	}
 	Repositories struct {
 		Repositories RepositoriesPage `json:"repositories"`
@@ -321,17 +644,27 @@ type (
 type (
 	ContributionsCollection struct {
 		ContributionsCollection struct {
-			ContributionYears                   []int `json:"contributionYears"`
-			TotalCommitContributions            int   `json:"totalCommitContributions"`
-			TotalIssueContributions             int   `json:"totalIssueContributions"`
-			TotalPullRequestContributions       int   `json:"totalPullRequestContributions"`
-			TotalPullRequestReviewContributions int   `json:"totalPullRequestReviewContributions"`
+			ContributionYears                   []int        `json:"contributionYears"`
+			ContributionCalendar                CalendarData `json:"contributionCalendar"`
+			TotalCommitContributions            int          `json:"totalCommitContributions"`
+			TotalIssueContributions             int          `json:"totalIssueContributions"`
+			TotalPullRequestContributions       int          `json:"totalPullRequestContributions"`
+			TotalPullRequestReviewContributions int          `json:"totalPullRequestReviewContributions"`
 		} `json:"contributionsCollection"`
 	}
 	ContributionCalendar struct {
-		ContributionCalendar struct {
-			TotalContributions int `json:"TotalContributions"`
-		} `json:"contributionCalendar"`
+		ContributionCalendar CalendarData `json:"contributionCalendar"`
+	}
+	CalendarData struct {
+		TotalContributions int `json:"totalContributions"`
+		Weeks              []struct {
+			ContributionDays []CalendarDay `json:"contributionDays"`
+		} `json:"weeks"`
+	}
+	CalendarDay struct {
+		Date              string `json:"date"`
+		ContributionCount int    `json:"contributionCount"`
+		Color             string `json:"color"`
 	}
 	AllContribYears = map[string]ContributionCalendar
 )
@@ -350,13 +683,34 @@ type (
 		} `json:"author"`
 	}
 
+	TrafficCount struct {
+		Timestamp time.Time `json:"timestamp"`
+		Count     int       `json:"count"`
+		Uniques   int       `json:"uniques"`
+	}
+
 	RepoTraffic struct {
-		Count   int `json:"count"`
-		Uniques int `json:"uniques"`
-		Views   []struct {
-			Timestamp time.Time `json:"timestamp"`
-			Count     int       `json:"count"`
-			Uniques   int       `json:"uniques"`
-		} `json:"views"`
+		Count   int            `json:"count"`
+		Uniques int            `json:"uniques"`
+		Views   []TrafficCount `json:"views"`
+	}
+
+	RepoClones struct {
+		Count   int            `json:"count"`
+		Uniques int            `json:"uniques"`
+		Clones  []TrafficCount `json:"clones"`
+	}
+
+	RepoReferrer struct {
+		Referrer string `json:"referrer"`
+		Count    int    `json:"count"`
+		Uniques  int    `json:"uniques"`
+	}
+
+	RepoPopularPath struct {
+		Path    string `json:"path"`
+		Title   string `json:"title"`
+		Count   int    `json:"count"`
+		Uniques int    `json:"uniques"`
 	}
 )
@@ -0,0 +1,76 @@
+package query
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// newEnterpriseServer serves REST under /api/v3 and GraphQL under
+// /api/graphql like GitHub Enterprise Server.
+func newEnterpriseServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"login":"bob"}`))
+	})
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"data":{"viewer":{"login":"bob"}}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestResolveEndpoints(t *testing.T) {
+	server := newEnterpriseServer(t)
+	tests := []struct {
+		name        string
+		restURL     string
+		graphqlURL  string
+		wantREST    string
+		wantGraphQL string
+	}{
+		{"default", "", "", DefaultRESTURL, DefaultGraphQLURL},
+		{"bare host", server.URL, "", server.URL + "/api/v3", server.URL + "/api/graphql"},
+		{"bare host with slash", server.URL + "/", "", server.URL + "/api/v3", server.URL + "/api/graphql"},
+		{"explicit api path", server.URL + "/api/v3/", "", server.URL + "/api/v3", server.URL + "/api/graphql"},
+		{"explicit graphql", server.URL, server.URL + "/api/graphql/", server.URL + "/api/v3", server.URL + "/api/graphql"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			restURL, graphqlURL := ResolveEndpoints(tt.restURL, tt.graphqlURL)
+			if restURL != tt.wantREST || graphqlURL != tt.wantGraphQL {
+				t.Fatalf("ResolveEndpoints(%q, %q) = %q, %q, want %q, %q", tt.restURL, tt.graphqlURL, restURL, graphqlURL, tt.wantREST, tt.wantGraphQL)
+			}
+			if restURL == DefaultRESTURL {
+				return
+			}
+			q := NewQueries("ghp_x", WithEndpoints(tt.restURL, tt.graphqlURL))
+			if _, err := q.requestRest(context.Background(), "user", nil); err != nil {
+				t.Errorf("REST request: %v", err)
+			}
+			if _, err := q.requestGraphql(context.Background(), "{viewer{login}}", nil); err != nil {
+				t.Errorf("GraphQL request: %v", err)
+			}
+		})
+	}
+}
+
+func TestRepoContributorsNoContent(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/bob/empty/stats/contributors", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	contributors, err := NewQueries("ghp_x", WithEndpoints(server.URL, "")).RepoContributors(context.Background(), "bob/empty")
+	if err != nil {
+		t.Fatalf("RepoContributors: %v", err)
+	}
+	if contributors == nil || len(*contributors) != 0 {
+		t.Errorf("contributors = %v, want none", contributors)
+	}
+}
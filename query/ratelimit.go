@@ -0,0 +1,134 @@
+package query
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	restResource    = "core"
+	graphqlResource = "graphql"
+
+	// GitHub asks clients to back off for at least a minute when a secondary
+	// rate limit response carries no Retry-After header.
+	secondaryRateLimitPause = time.Minute
+	maxThrottledRetries     = 5
+)
+
+type rateBudget struct {
+	remaining int
+	reset     time.Time
+}
+
+// scheduler is shared by every request of a Queries instance, so one throttled
+// response pauses all goroutines instead of letting them hammer the API.
+type scheduler struct {
+	mu          sync.Mutex
+	budgets     map[string]*rateBudget
+	pausedUntil time.Time
+}
+
+func newScheduler() *scheduler {
+	return &scheduler{
+		budgets: make(map[string]*rateBudget),
+	}
+}
+
+func (s *scheduler) wait(ctx context.Context, resource string) error {
+	for {
+		delay := s.reserve(resource)
+		if delay <= 0 {
+			return nil
+		}
+		log.Printf("Rate limit reached for %s, waiting %s", resource, delay.Round(time.Second))
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve returns how long the caller has to wait, or takes one unit of the
+// budget when the request may go out right away.
+func (s *scheduler) reserve(resource string) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Before(s.pausedUntil) {
+		return s.pausedUntil.Sub(now)
+	}
+	budget, ok := s.budgets[resource]
+	if !ok {
+		return 0
+	}
+	if budget.remaining <= 0 {
+		if now.Before(budget.reset) {
+			return budget.reset.Sub(now)
+		}
+		delete(s.budgets, resource)
+		return 0
+	}
+	budget.remaining--
+	return 0
+}
+
+func (s *scheduler) update(resource string, header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.budgets[resource] = &rateBudget{
+		remaining: remaining,
+		reset:     time.Unix(reset, 0),
+	}
+}
+
+func (s *scheduler) pause(d time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := time.Now().Add(d); until.After(s.pausedUntil) {
+		s.pausedUntil = until
+	}
+}
+
+// throttled records the rate limit state of a response and reports whether it
+// was rejected by the primary or the secondary rate limit.
+func (s *scheduler) throttled(resource string, resp *http.Response, body []byte) bool {
+	s.update(resource, resp.Header)
+	if resp.StatusCode != http.StatusTooManyRequests && resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		s.pause(time.Duration(seconds) * time.Second)
+		return true
+	}
+	if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || bytes.Contains(bytes.ToLower(body), []byte("secondary rate limit")) {
+		s.pause(secondaryRateLimitPause)
+		return true
+	}
+	return false
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
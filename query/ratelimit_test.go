@@ -0,0 +1,169 @@
+package query
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func throttledResponse(status int, header map[string]string) *http.Response {
+	resp := &http.Response{StatusCode: status, Header: make(http.Header)}
+	for key, value := range header {
+		resp.Header.Set(key, value)
+	}
+	return resp
+}
+
+// near reports whether d is within a second below want, the scheduler
+// measures from its own clock reading.
+func near(d, want time.Duration) bool {
+	return d <= want && d > want-time.Second
+}
+
+func TestSchedulerRetryAfter(t *testing.T) {
+	s := newScheduler()
+	resp := throttledResponse(http.StatusForbidden, map[string]string{"Retry-After": "30"})
+	if !s.throttled(restResource, resp, nil) {
+		t.Fatal("a 403 with Retry-After is throttled")
+	}
+	// The pause holds back every resource, not only the throttled one.
+	for _, resource := range []string{restResource, graphqlResource} {
+		if delay := s.reserve(resource); !near(delay, 30*time.Second) {
+			t.Errorf("reserve(%s) = %s, want about 30s", resource, delay)
+		}
+	}
+}
+
+func TestSchedulerExhaustedBudget(t *testing.T) {
+	s := newScheduler()
+	reset := time.Now().Add(45 * time.Second)
+	resp := throttledResponse(http.StatusForbidden, map[string]string{
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	})
+	if !s.throttled(restResource, resp, []byte(`{"message":"API rate limit exceeded"}`)) {
+		t.Fatal("a 403 with no remaining budget is throttled")
+	}
+	if delay := s.reserve(restResource); delay < 43*time.Second || delay > 45*time.Second {
+		t.Errorf("reserve(core) = %s, want until the reset in about 45s", delay)
+	}
+	if delay := s.reserve(graphqlResource); delay != 0 {
+		t.Errorf("reserve(graphql) = %s, the graphql budget is separate", delay)
+	}
+
+	// A budget whose reset passed is forgotten.
+	s.budgets[restResource].reset = time.Now().Add(-time.Second)
+	if delay := s.reserve(restResource); delay != 0 {
+		t.Errorf("reserve(core) after the reset = %s, want 0", delay)
+	}
+}
+
+func TestSchedulerCountsDownBudget(t *testing.T) {
+	s := newScheduler()
+	s.update(restResource, http.Header{
+		"X-Ratelimit-Remaining": {"2"},
+		"X-Ratelimit-Reset":     {strconv.FormatInt(time.Now().Add(time.Minute).Unix(), 10)},
+	})
+	for i := range 2 {
+		if delay := s.reserve(restResource); delay != 0 {
+			t.Fatalf("reserve %d = %s, want 0 while budget is left", i, delay)
+		}
+	}
+	if delay := s.reserve(restResource); delay <= 0 {
+		t.Errorf("reserve with the budget used up = %s, want a wait", delay)
+	}
+}
+
+func TestSchedulerSecondaryLimit(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		body      string
+		throttled bool
+	}{
+		{"secondary rate limit 403", http.StatusForbidden, `{"message":"You have exceeded a secondary rate limit."}`, true},
+		{"429 without headers", http.StatusTooManyRequests, ``, true},
+		{"plain 403", http.StatusForbidden, `{"message":"Resource not accessible by integration"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newScheduler()
+			if got := s.throttled(restResource, throttledResponse(tt.status, nil), []byte(tt.body)); got != tt.throttled {
+				t.Fatalf("throttled = %v, want %v", got, tt.throttled)
+			}
+			want := time.Duration(0)
+			if tt.throttled {
+				want = secondaryRateLimitPause
+			}
+			if delay := s.reserve(restResource); delay != want && !near(delay, want) {
+				t.Errorf("reserve = %s, want %s", delay, want)
+			}
+		})
+	}
+}
+
+func TestSendRequestThrottledRetries(t *testing.T) {
+	var requests atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/bob/alpha", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+	mux.HandleFunc("POST /api/graphql", func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Retry-After", "0")
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = fmt.Fprint(w, `{"message":"You have exceeded a secondary rate limit."}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+	q := NewQueries("ghp_x", WithEndpoints(server.URL, ""))
+
+	_, err := sendRequest[struct{}](context.Background(), q, "repos/bob/alpha", 60, nil)
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("sendRequest error = %v, want %v", err, ErrTooManyRequests)
+	}
+	if n := requests.Swap(0); n != maxThrottledRetries+1 {
+		t.Errorf("REST requests = %d, want %d", n, maxThrottledRetries+1)
+	}
+
+	_, err = sendGraphql[struct{}](context.Background(), q, "query { viewer { login } }", map[string]any{})
+	if !errors.Is(err, ErrTooManyRequests) {
+		t.Errorf("sendGraphql error = %v, want %v", err, ErrTooManyRequests)
+	}
+	if n := requests.Load(); n != maxThrottledRetries+1 {
+		t.Errorf("GraphQL requests = %d, want %d", n, maxThrottledRetries+1)
+	}
+}
+
+func TestSendRequestWaitsForRetryAfter(t *testing.T) {
+	var requests atomic.Int32
+	var retriedAt time.Time
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/repos/bob/alpha", func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		retriedAt = time.Now()
+		_, _ = fmt.Fprint(w, `{}`)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	start := time.Now()
+	if _, err := sendRequest[struct{}](context.Background(), NewQueries("ghp_x", WithEndpoints(server.URL, "")), "repos/bob/alpha", 60, nil); err != nil {
+		t.Fatal(err)
+	}
+	if wait := retriedAt.Sub(start); wait < time.Second {
+		t.Errorf("retried after %s, want at least the Retry-After of 1s", wait)
+	}
+}
@@ -0,0 +1,129 @@
+package query
+
+import (
+	"context"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type TokenType string
+
+const (
+	TokenTypeClassic      TokenType = "classic"
+	TokenTypeFineGrained  TokenType = "fine-grained"
+	TokenTypeOAuth        TokenType = "oauth"
+	TokenTypeInstallation TokenType = "installation"
+	TokenTypeUnknown      TokenType = "unknown"
+)
+
+type RateLimit struct {
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Reset     time.Time `json:"reset"`
+}
+
+type TokenInfo struct {
+	Type TokenType `json:"type"`
+	// Scopes is nil when GitHub does not report scopes, which is the case
+	// for fine-grained and installation tokens.
+	Scopes    []string  `json:"scopes"`
+	RateLimit RateLimit `json:"rateLimit"`
+}
+
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+type StaticToken string
+
+func (t StaticToken) Token(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// TokenInfo inspects the access token through GET /user. Installation tokens
+// do not belong to a user and get 403 there, they are inspected through
+// GET /rate_limit instead.
+func (q *Queries) TokenInfo(ctx context.Context) (*TokenInfo, error) {
+	token, err := q.tokens.Token(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info := &TokenInfo{Type: tokenType(token)}
+	if _, ok := q.tokens.(*AppTokenSource); ok {
+		info.Type = TokenTypeInstallation
+	}
+	path := "user"
+	if info.Type == TokenTypeInstallation {
+		path = "rate_limit"
+	}
+	resp, _, err := q.doRest(ctx, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	info.Scopes = tokenScopes(resp.Header)
+	info.RateLimit = rateLimit(resp.Header)
+	if info.Type == TokenTypeUnknown && info.Scopes != nil {
+		// Tokens created before the ghp_ prefix was introduced.
+		info.Type = TokenTypeClassic
+	}
+	return info, nil
+}
+
+func (t *TokenInfo) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// CanReadPrivateRepos is false only when the token reports scopes and repo is
+// not among them, other token types are limited per repository instead.
+func (t *TokenInfo) CanReadPrivateRepos() bool {
+	return t.Scopes == nil || t.HasScope("repo")
+}
+
+// CanReadTraffic reports whether the traffic endpoints can succeed for
+// repositories the token has push access to.
+func (t *TokenInfo) CanReadTraffic() bool {
+	return t.Scopes == nil || t.HasScope("repo") || t.HasScope("public_repo")
+}
+
+func tokenType(token string) TokenType {
+	switch {
+	case strings.HasPrefix(token, "ghp_"):
+		return TokenTypeClassic
+	case strings.HasPrefix(token, "github_pat_"):
+		return TokenTypeFineGrained
+	case strings.HasPrefix(token, "gho_"):
+		return TokenTypeOAuth
+	case strings.HasPrefix(token, "ghs_"):
+		return TokenTypeInstallation
+	default:
+		return TokenTypeUnknown
+	}
+}
+
+func tokenScopes(header http.Header) []string {
+	values, ok := header["X-Oauth-Scopes"]
+	if !ok {
+		return nil
+	}
+	scopes := []string{}
+	for _, scope := range strings.Split(strings.Join(values, ","), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+func rateLimit(header http.Header) RateLimit {
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	reset, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	return RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(reset, 0),
+	}
+}
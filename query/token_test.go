@@ -0,0 +1,156 @@
+package query
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+// newInstallationServer mocks the endpoints GitHub serves to an app
+// installation, GET /user is forbidden like on github.com.
+func newInstallationServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/v3/app/installations/42/access_tokens", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = fmt.Fprintf(w, `{"token":"ghs_installation","expires_at":%q}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	})
+	mux.HandleFunc("GET /api/v3/rate_limit", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token ghs_installation" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("X-RateLimit-Limit", "5000")
+		w.Header().Set("X-RateLimit-Remaining", "4990")
+		w.Header().Set("X-RateLimit-Reset", "1700000000")
+		_, _ = w.Write([]byte(`{"resources":{}}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func testPrivateKey(t *testing.T) []byte {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+}
+
+func TestTokenInfoInstallation(t *testing.T) {
+	server := newInstallationServer(t)
+	source, err := NewAppTokenSource(server.URL, "1", 42, testPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewQueries("", WithEndpoints(server.URL, ""), WithTokenSource(source))
+	info, err := q.TokenInfo(context.Background())
+	if err != nil {
+		t.Fatalf("TokenInfo: %v", err)
+	}
+	if info.Type != TokenTypeInstallation {
+		t.Errorf("Type = %q, want %q", info.Type, TokenTypeInstallation)
+	}
+	if info.Scopes != nil {
+		t.Errorf("Scopes = %v, want nil", info.Scopes)
+	}
+	if info.RateLimit.Limit != 5000 || info.RateLimit.Remaining != 4990 {
+		t.Errorf("RateLimit = %+v, want 4990/5000", info.RateLimit)
+	}
+	if !info.CanReadPrivateRepos() || !info.CanReadTraffic() {
+		t.Error("installation tokens should not be degraded")
+	}
+}
+
+func TestTokenInfoClassic(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-OAuth-Scopes", "public_repo, read:user")
+		_, _ = w.Write([]byte(`{"login":"bob"}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	info, err := NewQueries("ghp_classic", WithEndpoints(server.URL, "")).TokenInfo(context.Background())
+	if err != nil {
+		t.Fatalf("TokenInfo: %v", err)
+	}
+	if info.Type != TokenTypeClassic {
+		t.Errorf("Type = %q, want %q", info.Type, TokenTypeClassic)
+	}
+	if want := []string{"public_repo", "read:user"}; !slices.Equal(info.Scopes, want) {
+		t.Errorf("Scopes = %v, want %v", info.Scopes, want)
+	}
+	if info.CanReadPrivateRepos() {
+		t.Error("CanReadPrivateRepos = true without the repo scope")
+	}
+}
+
+type countingTransport struct {
+	requests []string
+}
+
+func (c *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	c.requests = append(c.requests, req.Method+" "+req.URL.Path)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAppTokenSourceUsesTransport(t *testing.T) {
+	server := newInstallationServer(t)
+	source, err := NewAppTokenSource(server.URL, "1", 42, testPrivateKey(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport := &countingTransport{}
+	q := NewQueries("", WithEndpoints(server.URL, ""), WithTransport(transport), WithTokenSource(source))
+	if _, err = q.TokenInfo(context.Background()); err != nil {
+		t.Fatalf("TokenInfo: %v", err)
+	}
+	want := []string{"POST /api/v3/app/installations/42/access_tokens", "GET /api/v3/rate_limit"}
+	if !slices.Equal(transport.requests, want) {
+		t.Errorf("requests = %v, want %v", transport.requests, want)
+	}
+}
+
+type timedToken struct {
+	calledAt time.Time
+}
+
+func (t *timedToken) Token(context.Context) (string, error) {
+	t.calledAt = time.Now()
+	return "ghp_timed", nil
+}
+
+func TestTokenReadAfterRateLimitWait(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v3/user", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{}`))
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tokens := &timedToken{}
+	q := NewQueries("", WithEndpoints(server.URL, ""), WithTokenSource(tokens))
+	pausedUntil := time.Now().Add(100 * time.Millisecond)
+	q.scheduler.pausedUntil = pausedUntil
+	if _, err := q.requestRest(context.Background(), "user", nil); err != nil {
+		t.Fatal(err)
+	}
+	if tokens.calledAt.Before(pausedUntil) {
+		t.Errorf("token read %s before the pause ended", pausedUntil.Sub(tokens.calledAt))
+	}
+}
@@ -0,0 +1,178 @@
+package render
+
+import (
+	_ "embed"
+	"fmt"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+//go:embed templates/calendar.gohtml
+var calendarSVG string
+
+// Theme colors the calendar card. Levels go from days without contributions
+// to the busiest days.
+type Theme struct {
+	Background string
+	Border     string
+	Title      string
+	Text       string
+	Levels     [5]string
+}
+
+// DefaultTheme is used when no theme is given.
+const DefaultTheme = "light"
+
+// Themes are the built-in calendar themes by name, matching the colors of the
+// GitHub profile page.
+var Themes = map[string]Theme{
+	"light": {
+		Background: "#00000000",
+		Border:     "#8B8B8B22",
+		Title:      "rgb(107, 164, 248)",
+		Text:       "rgb(139, 139, 139)",
+		Levels:     [5]string{"#ebedf0", "#9be9a8", "#40c463", "#30a14e", "#216e39"},
+	},
+	"dark": {
+		Background: "#0d1117",
+		Border:     "#30363d",
+		Title:      "rgb(88, 166, 255)",
+		Text:       "rgb(139, 148, 158)",
+		Levels:     [5]string{"#161b22", "#0e4429", "#006d32", "#26a641", "#39d353"},
+	},
+}
+
+const (
+	calendarWeeks   = 53
+	calendarCell    = 10
+	calendarStep    = 13
+	calendarLeft    = 51
+	calendarTop     = 62
+	calendarPadding = 21
+)
+
+// CalendarTheme colors the card with theme instead of DefaultTheme.
+func CalendarTheme(theme Theme) Option {
+	return func(o *options) {
+		o.theme = theme
+	}
+}
+
+type CalendarCell struct {
+	X, Y  int
+	Color string
+	Title string
+	Delay int
+}
+
+type CalendarLabel struct {
+	X, Y int
+	Name string
+}
+
+// CalendarSVG renders the last year of data.Calendar as a heatmap like the
+// one on the GitHub profile page.
+func CalendarSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	var input struct {
+		Stats     *stats.Stats
+		Animation bool
+		Theme     Theme
+		Width     int
+		Height    int
+		CellSize  int
+		Title     string
+		Summary   string
+		SummaryX  int
+		MonthY    int
+		Months    []CalendarLabel
+		Weekdays  []CalendarLabel
+		Days      []CalendarCell
+		Legend    []CalendarCell
+		LegendX   int
+		LegendY   int
+		MoreX     int
+	}
+	input.Stats = data
+	input.Animation = animation
+	input.Theme = opts.theme
+	input.CellSize = calendarCell
+	input.Width = calendarLeft + calendarWeeks*calendarStep + calendarPadding
+	input.Height = calendarTop + 7*calendarStep + 40
+	input.Title = fmt.Sprintf("%s's Contributions", data.Name)
+	input.SummaryX = input.Width - calendarPadding
+	input.MonthY = calendarTop - 8
+
+	start, days := lastCalendarYear(data.Calendar)
+	maxCount, total := 0, 0
+	for _, day := range days {
+		maxCount = max(maxCount, day.Count)
+		total += day.Count
+	}
+	period := "in the last year"
+	if data.Window != nil {
+		period = "in " + data.Window.Label
+	}
+	input.Summary = fmt.Sprintf("%d contributions %s", total, period)
+
+	if len(days) > 0 {
+		lastMonth := time.Month(0)
+		for _, day := range days {
+			week := int(day.Date.Sub(start).Hours()) / 24 / 7
+			x := calendarLeft + week*calendarStep
+			if day.Date.Day() <= 7 && day.Date.Month() != lastMonth && day.Date.Weekday() == time.Sunday {
+				lastMonth = day.Date.Month()
+				input.Months = append(input.Months, CalendarLabel{X: x, Name: day.Date.Format("Jan")})
+			}
+			input.Days = append(input.Days, CalendarCell{
+				X:     x,
+				Y:     calendarTop + int(day.Date.Weekday())*calendarStep,
+				Color: opts.theme.Levels[calendarLevel(day.Count, maxCount)],
+				Title: fmt.Sprintf("%d contributions on %s", day.Count, day.Date.Format("Jan 2, 2006")),
+				Delay: week * 20,
+			})
+		}
+	}
+	for i, name := range []string{"Mon", "Wed", "Fri"} {
+		input.Weekdays = append(input.Weekdays, CalendarLabel{Y: calendarTop + (2*i+1)*calendarStep + calendarCell - 1, Name: name})
+	}
+
+	input.LegendY = calendarTop + 7*calendarStep + 20
+	input.MoreX = input.Width - calendarPadding - 26
+	for i, color := range opts.theme.Levels {
+		input.Legend = append(input.Legend, CalendarCell{
+			X:     input.MoreX - 4 - (len(opts.theme.Levels)-i)*calendarStep,
+			Y:     input.LegendY - calendarCell + 1,
+			Color: color,
+		})
+	}
+	input.LegendX = input.Legend[0].X - 4
+
+	return opts.execute("calendar", calendarSVG, input)
+}
+
+// lastCalendarYear returns the Sunday starting the first column and the days
+// of the last calendarWeeks weeks, so the newest day is in the last column.
+func lastCalendarYear(days []stats.CalendarDay) (time.Time, []stats.CalendarDay) {
+	if len(days) == 0 {
+		return time.Time{}, nil
+	}
+	last := days[len(days)-1].Date
+	first := last.AddDate(0, 0, -int(last.Weekday())-(calendarWeeks-1)*7)
+	for i, day := range days {
+		if !day.Date.Before(first) {
+			return first, days[i:]
+		}
+	}
+	return first, nil
+}
+
+// calendarLevel maps count to one of the five theme levels, relative to the
+// busiest day.
+func calendarLevel(count, maxCount int) int {
+	if count <= 0 || maxCount <= 0 {
+		return 0
+	}
+	return 1 + min(3, (count-1)*4/maxCount)
+}
@@ -3,7 +3,6 @@ package render
 import (
 	"embed"
 	"fmt"
-	"maps"
 	"os"
 	"slices"
 	"strings"
@@ -19,6 +18,21 @@ var overviewSVG string
 //go:embed templates/languages.gohtml
 var languagesSVG string
 
+//go:embed templates/traffic.gohtml
+var trafficSVG string
+
+//go:embed templates/activity.gohtml
+var activitySVG string
+
+//go:embed templates/streak.gohtml
+var streakSVG string
+
+//go:embed templates/toprepos.gohtml
+var topReposSVG string
+
+//go:embed templates/repo.gohtml
+var repoSVG string
+
 //go:embed icons/*.svg
 var iconsFS embed.FS
 
@@ -32,6 +46,85 @@ type OverviewItem struct {
 	Icon  string
 	Name  string
 	Value string
+	// Delta is the signed change since the compared snapshot, empty when
+	// there is none.
+	Delta string
+}
+
+// DeltaClass is the CSS class of the delta, decreases are styled apart from
+// increases.
+func (i OverviewItem) DeltaClass() string {
+	if strings.HasPrefix(i.Delta, "-") {
+		return "delta negative"
+	}
+	return "delta"
+}
+
+type options struct {
+	delta     *stats.Delta
+	theme     Theme
+	by        string
+	count     int
+	templates *Templates
+}
+
+// Option configures a card, options that do not apply to it are ignored.
+type Option func(*options)
+
+func newOptions(opts []Option) options {
+	o := options{theme: Themes[DefaultTheme], by: "stars", count: DefaultTopReposCount}
+	for _, option := range opts {
+		option(&o)
+	}
+	return o
+}
+
+// WithDelta shows the changes in delta next to the matching values on the
+// overview card.
+func WithDelta(delta *stats.Delta) Option {
+	return func(o *options) {
+		o.delta = delta
+	}
+}
+
+// WithTemplates renders the cards with the templates that replace a built-in
+// one in templates.
+func WithTemplates(templates *Templates) Option {
+	return func(o *options) {
+		o.templates = templates
+	}
+}
+
+// execute renders input with the template called name, taken from
+// o.templates when it replaces builtin.
+func (o options) execute(name, builtin string, input any) (SVGData, error) {
+	source := builtin
+	if override, ok := o.templates.lookup(name); ok {
+		source = override
+	}
+	tmpl, err := template.New(name).Funcs(FuncMap()).Parse(source)
+	if err != nil {
+		return "", fmt.Errorf("parse %s template: %w", name, err)
+	}
+	var buf strings.Builder
+	if err = tmpl.Execute(&buf, input); err != nil {
+		return "", fmt.Errorf("render %s template: %w", name, err)
+	}
+	return SVGData(buf.String()), nil
+}
+
+func formatDelta(change int) string {
+	if change == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+d", change)
+}
+
+func formatOptionalDelta(change *int) string {
+	if change == nil {
+		return ""
+	}
+	return formatDelta(*change)
 }
 
 func loadIcon(name string) string {
@@ -42,98 +135,360 @@ func loadIcon(name string) string {
 	return string(f)
 }
 
-func OverviewSVG(animation bool, data *stats.Stats) (SVGData, error) {
+func OverviewSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	delta := opts.delta
+	if delta == nil {
+		delta = &stats.Delta{}
+	}
 	var input struct {
+		Stats     *stats.Stats
 		Name      string
 		Animation bool
 		Items     []OverviewItem
 	}
 	input.Name = data.Name
+	input.Stats = data
 	input.Animation = animation
 	input.Items = append(input.Items, OverviewItem{
 		Icon:  loadIcon("star"),
 		Name:  "Stars",
 		Value: fmt.Sprintf("%d", data.Stargazers),
+		Delta: formatDelta(delta.Stargazers),
 	})
 	input.Items = append(input.Items, OverviewItem{
 		Icon:  loadIcon("repo-forked"),
 		Name:  "Forks",
 		Value: fmt.Sprintf("%d", data.Forks),
+		Delta: formatDelta(delta.Forks),
 	})
+	// Sections that failed to load are left out instead of showing zeros,
+	// the failure itself is listed in data.Errors.
+	// The labels name the window when the stats are limited to one.
+	period := fmt.Sprintf("%d", time.Now().Year())
+	linesName, contributionsName := "Lines of code changed", "All-time contributions"
+	if data.Window != nil {
+		period = data.Window.Label
+		linesName = fmt.Sprintf("Lines of code changed (%s)", period)
+		contributionsName = fmt.Sprintf("Contributions (%s)", period)
+	}
 	if data.LineChange != nil {
 		input.Items = append(input.Items, OverviewItem{
 			Icon:  loadIcon("diff"),
-			Name:  "Lines of code changed",
+			Name:  linesName,
 			Value: fmt.Sprintf("%d", data.LineChange.Additions+data.LineChange.Deletions),
+			Delta: formatOptionalDelta(delta.LinesChanged),
 		})
-	} else {
+	} else if data.Contributions != nil {
 		input.Items = append(input.Items, OverviewItem{
 			Icon:  loadIcon("git-commit"),
-			Name:  fmt.Sprintf("Total commits (%d)", time.Now().Year()),
+			Name:  fmt.Sprintf("Total commits (%s)", period),
 			Value: fmt.Sprintf("%d", data.Contributions.TotalCommitContributions),
 		})
 	}
 	if data.Views != nil {
+		// Without earlier runs the all-time total only covers the two weeks
+		// GitHub reports.
+		name := "Repository views (past two weeks)"
+		if !data.Views.Since.IsZero() && time.Since(data.Views.Since) > 15*24*time.Hour {
+			name = fmt.Sprintf("Repository views (since %s)", data.Views.Since.Format("Jan 2006"))
+		}
 		input.Items = append(input.Items, OverviewItem{
 			Icon:  loadIcon("eye"),
-			Name:  "Repository views (past two weeks)",
-			Value: fmt.Sprintf("%d", data.Views.Count),
+			Name:  name,
+			Value: fmt.Sprintf("%d", data.Views.AllTime.Count),
 		})
-	} else {
+	} else if data.Contributions != nil {
 		input.Items = append(input.Items, OverviewItem{
 			Icon:  loadIcon("git-pull-request"),
-			Name:  fmt.Sprintf("Total pull requests (%d)", time.Now().Year()),
+			Name:  fmt.Sprintf("Total pull requests (%s)", period),
 			Value: fmt.Sprintf("%d", data.Contributions.TotalPullRequestContributions),
 		})
 	}
-	input.Items = append(input.Items, OverviewItem{
-		Icon:  loadIcon("repo-push"),
-		Name:  "All-time contributions",
-		Value: fmt.Sprintf("%d", data.Contributions.TotalContributions),
-	})
+	if data.Contributions != nil {
+		input.Items = append(input.Items, OverviewItem{
+			Icon:  loadIcon("repo-push"),
+			Name:  contributionsName,
+			Value: fmt.Sprintf("%d", data.Contributions.TotalContributions),
+			Delta: formatOptionalDelta(delta.Contributions),
+		})
+	}
 	input.Items = append(input.Items, OverviewItem{
 		Icon:  loadIcon("repo"),
 		Name:  "Repositories with contributions",
 		Value: fmt.Sprintf("%d", len(data.Repos)),
 	})
 
-	tmpl, err := template.New("overview").Parse(overviewSVG)
-	if err != nil {
-		return "", err
+	return opts.execute("overview", overviewSVG, input)
+}
+
+func LanguagesSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	var input struct {
+		Stats     *stats.Stats
+		Animation bool
+		Languages []*stats.LanguageStats
 	}
-	var buf strings.Builder
-	err = tmpl.Execute(&buf, input)
-	if err != nil {
-		return "", err
+	input.Stats = data
+	input.Animation = animation
+	input.Languages = sortedLanguages(data)
+	return opts.execute("languages", languagesSVG, input)
+}
+
+// trafficListSize is the number of referrers and repositories on the traffic
+// card.
+const trafficListSize = 5
+
+type TrafficItem struct {
+	Name  string
+	Count int
+}
+
+// TrafficSVG renders the top referrers and the most visited repositories of
+// the last 14 days. data.Views is nil when traffic was not loaded.
+func TrafficSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	var input struct {
+		Stats     *stats.Stats
+		Animation bool
+		Views     int
+		Clones    int
+		Referrers []TrafficItem
+		Repos     []TrafficItem
 	}
-	return SVGData(buf.String()), nil
+	input.Stats = data
+	input.Animation = animation
+	if data.Views != nil {
+		input.Views = data.Views.Count
+	}
+	if data.Clones != nil {
+		input.Clones = data.Clones.Count
+	}
+	for _, referrer := range data.Referrers {
+		input.Referrers = append(input.Referrers, TrafficItem{Name: referrer.Referrer, Count: referrer.Count})
+	}
+	for _, repo := range data.Repos {
+		if repo != nil && repo.Traffic != nil && repo.Traffic.Views.Count > 0 {
+			input.Repos = append(input.Repos, TrafficItem{Name: repo.Name, Count: repo.Traffic.Views.Count})
+		}
+	}
+	slices.SortFunc(input.Repos, func(a, b TrafficItem) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	input.Referrers = input.Referrers[:min(len(input.Referrers), trafficListSize)]
+	input.Repos = input.Repos[:min(len(input.Repos), trafficListSize)]
+	return opts.execute("traffic", trafficSVG, input)
+}
+
+// activityListSize is the number of repositories on the activity card.
+const activityListSize = 5
+
+type ActivityItem struct {
+	Name    string
+	Lines   string
+	Commits string
+	Views   string
 }
 
-func LanguagesSVG(animation bool, data *stats.Stats) (SVGData, error) {
+// ActivitySVG renders the repositories with the most lines changed, or the
+// most views when lines changed are not loaded, so a jump in the overview
+// numbers can be traced to a repository.
+func ActivitySVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
 	var input struct {
+		Stats     *stats.Stats
 		Animation bool
-		Languages []*stats.LanguageStats
+		Title     string
+		Repos     []ActivityItem
 	}
-	funcMap := template.FuncMap{
-		"AnimationDelay": func(i int) int {
-			return i * 150
-		},
-		"Percent": func(i float64) string {
-			return fmt.Sprintf("%.3f%%", i)
-		},
+	input.Stats = data
+	input.Animation = animation
+	input.Title = "Most changed repositories"
+	metric := stats.RepoLinesChanged
+	if data.LineChange == nil {
+		input.Title = "Most viewed repositories"
+		metric = stats.RepoViews
+	} else if data.Window != nil {
+		input.Title = fmt.Sprintf("Most changed (%s)", data.Window.Label)
 	}
-	tmpl, err := template.New("languages").Funcs(funcMap).Parse(languagesSVG)
-	if err != nil {
-		return "", err
+	for _, rank := range stats.RankRepos(data, metric, activityListSize) {
+		repo := data.Repos[rank.Name]
+		item := ActivityItem{Name: repo.Name, Lines: "-", Commits: "-", Views: "-"}
+		if repo.LineChange != nil {
+			item.Lines = fmt.Sprintf("+%d/-%d", repo.LineChange.Additions, repo.LineChange.Deletions)
+			item.Commits = fmt.Sprintf("%d", repo.LineChange.Commits)
+		}
+		if repo.Traffic != nil {
+			item.Views = fmt.Sprintf("%d", repo.Traffic.Views.Count)
+		}
+		input.Repos = append(input.Repos, item)
+	}
+	return opts.execute("activity", activitySVG, input)
+}
+
+// StreakSVG renders the current and longest contribution streaks of
+// data.Streaks in the style of the overview card.
+func StreakSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	var input struct {
+		Stats     *stats.Stats
+		Name      string
+		Animation bool
+		Items     []OverviewItem
 	}
+	input.Name = data.Name
+	input.Stats = data
 	input.Animation = animation
-	input.Languages = slices.SortedFunc(maps.Values(data.Languages), func(s1 *stats.LanguageStats, s2 *stats.LanguageStats) int {
-		return s2.Size - s1.Size
+	streaks := data.Streaks
+	if streaks == nil {
+		streaks = &stats.StreakStats{}
+	}
+	input.Items = append(input.Items, OverviewItem{
+		Icon:  loadIcon("git-commit"),
+		Name:  streakName("Current streak", streaks.Current),
+		Value: formatDays(streaks.Current.Length),
 	})
-	var buf strings.Builder
-	err = tmpl.Execute(&buf, input)
-	if err != nil {
-		return "", err
+	input.Items = append(input.Items, OverviewItem{
+		Icon:  loadIcon("star"),
+		Name:  streakName("Longest streak", streaks.Longest),
+		Value: formatDays(streaks.Longest.Length),
+	})
+	input.Items = append(input.Items, OverviewItem{
+		Icon:  loadIcon("repo-push"),
+		Name:  "Days with contributions",
+		Value: formatDays(streaks.ActiveDays),
+	})
+
+	return opts.execute("streak", streakSVG, input)
+}
+
+func streakName(name string, streak stats.Streak) string {
+	if streak.Length == 0 {
+		return name
 	}
-	return SVGData(buf.String()), nil
+	if streak.Start.Equal(streak.End) {
+		return fmt.Sprintf("%s (%s)", name, streak.Start.Format("Jan 2, 2006"))
+	}
+	start := streak.Start.Format("Jan 2")
+	if streak.Start.Year() != streak.End.Year() {
+		start = streak.Start.Format("Jan 2, 2006")
+	}
+	return fmt.Sprintf("%s (%s - %s)", name, start, streak.End.Format("Jan 2, 2006"))
+}
+
+func formatDays(days int) string {
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// DefaultTopReposCount is the number of repositories on the top repositories
+// card when no count is given.
+const DefaultTopReposCount = 5
+
+// TopReposMetrics are the rankings TopReposBy accepts, with the unit shown
+// next to each value.
+var TopReposMetrics = map[string]struct {
+	Metric stats.RepoMetric
+	Unit   string
+}{
+	"stars": {stats.RepoStargazers, "stars"},
+	"forks": {stats.RepoForks, "forks"},
+	"churn": {stats.RepoLinesChanged, "lines"},
+	"views": {stats.RepoViews, "views"},
+}
+
+// TopReposBy ranks by one of TopReposMetrics instead of stars.
+func TopReposBy(by string) Option {
+	return func(o *options) {
+		o.by = by
+	}
+}
+
+// TopReposCount lists count repositories instead of DefaultTopReposCount.
+func TopReposCount(count int) Option {
+	return func(o *options) {
+		o.count = count
+	}
+}
+
+type TopRepoItem struct {
+	Name        string
+	Description string
+	Color       string
+	Value       string
+}
+
+// TopReposSVG renders the highest ranked repositories with their primary
+// language and description. Ignored and excluded repositories are left out.
+func TopReposSVG(animation bool, data *stats.Stats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	metric, ok := TopReposMetrics[opts.by]
+	if !ok {
+		return "", fmt.Errorf("unknown top repositories ranking %q", opts.by)
+	}
+	var input struct {
+		Stats         *stats.Stats
+		Animation     bool
+		Title         string
+		Height        int
+		ContentHeight int
+		Repos         []TopRepoItem
+	}
+	input.Stats = data
+	input.Animation = animation
+	input.Title = fmt.Sprintf("Top Repositories by %s", metric.Unit)
+	for _, rank := range stats.RankRepos(data, stats.Listed(metric.Metric), opts.count) {
+		repo := data.Repos[rank.Name]
+		item := TopRepoItem{
+			Name:        repo.Name,
+			Description: repo.Description,
+			Color:       "#8B8B8B",
+			Value:       fmt.Sprintf("%d %s", rank.Value, metric.Unit),
+		}
+		if repo.Language != nil && repo.Language.Color != "" {
+			item.Color = repo.Language.Color
+		}
+		input.Repos = append(input.Repos, item)
+	}
+	input.ContentHeight = 36 + max(len(input.Repos), 1)*40
+	input.Height = input.ContentHeight + 34
+	return opts.execute("toprepos", topReposSVG, input)
+}
+
+// RepoCardSVG renders a card for one repository like the pinned repositories
+// on a GitHub profile.
+func RepoCardSVG(animation bool, repo *stats.RepoStats, options ...Option) (SVGData, error) {
+	opts := newOptions(options)
+	var input struct {
+		Repo        *stats.RepoStats
+		Animation   bool
+		Name        string
+		Description string
+		Language    string
+		Color       string
+		Stars       int
+		Forks       int
+		RepoIcon    string
+		StarIcon    string
+		ForkIcon    string
+	}
+	input.Repo = repo
+	input.Animation = animation
+	input.Name = repo.Name
+	input.Description = repo.Description
+	input.Stars = repo.Stargazers
+	input.Forks = repo.Forks
+	if repo.Language != nil {
+		input.Language = repo.Language.Name
+		input.Color = repo.Language.Color
+	}
+	input.RepoIcon = loadIcon("repo")
+	input.StarIcon = loadIcon("star")
+	input.ForkIcon = loadIcon("repo-forked")
+	return opts.execute("repo", repoSVG, input)
 }
@@ -0,0 +1,153 @@
+package render
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TBXark/github-status/query"
+	"github.com/TBXark/github-status/stats"
+)
+
+// replayStats loads the stats recorded in the fixtures of the stats package.
+func replayStats(t *testing.T) *stats.Stats {
+	t.Helper()
+	data, err := stats.NewStats("bob", "ghp_replay",
+		stats.OnError(stats.ErrorPolicyFail),
+		stats.IncludeOwner("bob"),
+		stats.WithClock(func() time.Time { return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC) }),
+		stats.QueryOptions(
+			query.WithTransport(&query.ReplayTransport{Dir: "../stats/testdata/replay"}),
+			query.WithEndpoints("https://github.example.com", ""),
+		),
+	).GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	return data
+}
+
+// checkSVG fails unless svg is well-formed XML containing every one of want.
+func checkSVG(t *testing.T, svg SVGData, want ...string) {
+	t.Helper()
+	decoder := xml.NewDecoder(strings.NewReader(string(svg)))
+	for {
+		_, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid SVG: %v\n%s", err, svg)
+		}
+	}
+	for _, s := range want {
+		if !strings.Contains(string(svg), s) {
+			t.Errorf("SVG does not contain %q", s)
+		}
+	}
+}
+
+func TestCardsReplay(t *testing.T) {
+	data := replayStats(t)
+	tests := []struct {
+		name   string
+		render func(animation bool, data *stats.Stats, options ...Option) (SVGData, error)
+		want   []string
+	}{
+		{"overview", OverviewSVG, []string{"Stars", ">13<", ">3240<", ">200<"}},
+		{"languages", LanguagesSVG, []string{"Go", "72.622%", "Zig"}},
+		{"traffic", TrafficSVG, []string{"google.com", "bob/alpha"}},
+		{"activity", ActivitySVG, []string{"Most changed repositories", "+1100/-520"}},
+		{"calendar", CalendarSVG, []string{"bob's Contributions", "contributions in the last year"}},
+		{"streak", StreakSVG, []string{"6 days", "50 days"}},
+		{"toprepos", TopReposSVG, []string{"Top Repositories by stars", "bob/alpha", "12 stars"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, animation := range []bool{false, true} {
+				svg, err := tt.render(animation, data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				checkSVG(t, svg, tt.want...)
+			}
+		})
+	}
+	t.Run("repo", func(t *testing.T) {
+		svg, err := RepoCardSVG(false, data.Pinned[0])
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkSVG(t, svg, "bob/alpha", "Alpha &lt;project&gt; &amp; co")
+	})
+}
+
+func TestTemplates(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"languages.gohtml": `<svg>{{range .Languages}}<text>{{html .Name}}</text>{{end}}<text>{{.Stats.Name}}</text></svg>`,
+		"summary.gohtml":   `<svg><text>{{Number .Stats.Stargazers}} {{Compact 12345}}</text>{{range TopRepos .Stats "stars" 1}}<text>{{html .Name}}</text>{{end}}</svg>`,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	templates, err := LoadTemplates(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if extra := templates.Extra(); len(extra) != 1 || extra[0] != "summary" {
+		t.Fatalf("Extra = %v, want [summary]", extra)
+	}
+	data := replayStats(t)
+	languages, err := LanguagesSVG(false, data, WithTemplates(templates))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<svg><text>Go</text><text>Shell</text><text>Zig</text><text>bob</text></svg>`; string(languages) != want {
+		t.Errorf("languages = %s, want %s", languages, want)
+	}
+	summary, err := templates.RenderExtra("summary", false, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `<svg><text>13 12.3k</text><text>bob/alpha</text></svg>`; string(summary) != want {
+		t.Errorf("summary = %s, want %s", summary, want)
+	}
+	if _, err = templates.RenderExtra("languages", false, data); err == nil {
+		t.Error("RenderExtra rendered a built-in template")
+	}
+
+	if err = os.WriteFile(filepath.Join(dir, "broken.gohtml"), []byte(`{{range}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadTemplates(dir); err == nil || !strings.Contains(err.Error(), "broken.gohtml") {
+		t.Errorf("LoadTemplates = %v, want an error naming broken.gohtml", err)
+	}
+}
+
+func TestOverviewDeltaClass(t *testing.T) {
+	data := replayStats(t)
+	svg, err := OverviewSVG(false, data, WithDelta(&stats.Delta{Stargazers: -12, Forks: 2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, svg, `<span class="delta negative">-12</span>`, `<span class="delta">+2</span>`)
+}
+
+func TestTrafficEscapesNames(t *testing.T) {
+	data := replayStats(t)
+	data.Referrers = append(data.Referrers, &stats.ReferrerStats{Referrer: `<a href="x">&co</a>`, Count: 1})
+	svg, err := TrafficSVG(false, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkSVG(t, svg, "&lt;a href=&#34;x&#34;&gt;&amp;co&lt;/a&gt;")
+}
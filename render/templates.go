@@ -0,0 +1,176 @@
+package render
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+// builtinTemplates are the names of the templates a template directory can
+// replace, the file overview.gohtml replaces the overview card and so on.
+var builtinTemplates = []string{"overview", "languages", "traffic", "activity", "calendar", "streak", "toprepos", "repo"}
+
+// Templates are the *.gohtml files of a template directory by name, without
+// the extension.
+type Templates struct {
+	sources map[string]string
+}
+
+// LoadTemplates reads every *.gohtml file in dir. Files named after a built-in
+// template replace it, the others are rendered as extra cards by RenderExtra.
+// All files are parsed up front so mistakes are reported before any stats are
+// loaded.
+func LoadTemplates(dir string) (*Templates, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.gohtml"))
+	if err != nil {
+		return nil, err
+	}
+	templates := &Templates{sources: make(map[string]string, len(paths))}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(filepath.Base(path), ".gohtml")
+		if _, err = template.New(name).Funcs(FuncMap()).Parse(string(data)); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		templates.sources[name] = string(data)
+	}
+	return templates, nil
+}
+
+func (t *Templates) lookup(name string) (string, bool) {
+	if t == nil {
+		return "", false
+	}
+	source, ok := t.sources[name]
+	return source, ok
+}
+
+// Extra returns the sorted names of the templates that do not replace a
+// built-in one.
+func (t *Templates) Extra() []string {
+	if t == nil {
+		return nil
+	}
+	var names []string
+	for _, name := range slices.Sorted(maps.Keys(t.sources)) {
+		if !slices.Contains(builtinTemplates, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TemplateData is the input of the extra cards.
+type TemplateData struct {
+	Animation bool
+	Stats     *stats.Stats
+}
+
+// RenderExtra renders the extra card called name with data.
+func (t *Templates) RenderExtra(name string, animation bool, data *stats.Stats) (SVGData, error) {
+	source, ok := t.lookup(name)
+	if !ok || slices.Contains(builtinTemplates, name) {
+		return "", fmt.Errorf("no extra template %q", name)
+	}
+	opts := options{templates: t}
+	return opts.execute(name, source, TemplateData{Animation: animation, Stats: data})
+}
+
+// FuncMap returns the helpers available to every card template, built-in or
+// loaded from a template directory:
+//
+//	AnimationDelay i        the animation delay in ms of the i-th item
+//	Percent f               f formatted as a percentage, 12.345%
+//	Number n                n with thousands separators, 12,345
+//	Compact n               n shortened to 12.3k or 1.2M
+//	Add a b, Sub a b, Mul a b
+//	                        integer arithmetic for coordinates
+//	Icon name               the octicon name from the icons directory as SVG
+//	Date layout t           t formatted with the Go time layout
+//	SortedLanguages stats   the languages of stats, largest first
+//	TopRepos stats by n     the n highest ranked repositories of stats, by is
+//	                        one of TopReposMetrics
+//
+// Values taken from GitHub should be escaped with the html function of
+// text/template.
+func FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"AnimationDelay": func(i int) int {
+			return i * 150
+		},
+		"Percent": func(f float64) string {
+			return fmt.Sprintf("%.3f%%", f)
+		},
+		"Number":  formatNumber,
+		"Compact": formatCompact,
+		"Add": func(a, b int) int {
+			return a + b
+		},
+		"Sub": func(a, b int) int {
+			return a - b
+		},
+		"Mul": func(a, b int) int {
+			return a * b
+		},
+		"Icon": loadIcon,
+		"Date": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"SortedLanguages": sortedLanguages,
+		"TopRepos": func(data *stats.Stats, by string, n int) ([]*stats.RepoStats, error) {
+			metric, ok := TopReposMetrics[by]
+			if !ok {
+				return nil, fmt.Errorf("unknown top repositories ranking %q", by)
+			}
+			var repos []*stats.RepoStats
+			for _, rank := range stats.RankRepos(data, stats.Listed(metric.Metric), n) {
+				repos = append(repos, data.Repos[rank.Name])
+			}
+			return repos, nil
+		},
+	}
+}
+
+func sortedLanguages(data *stats.Stats) []*stats.LanguageStats {
+	return slices.SortedFunc(maps.Values(data.Languages), func(s1 *stats.LanguageStats, s2 *stats.LanguageStats) int {
+		return s2.Size - s1.Size
+	})
+}
+
+func formatNumber(n int) string {
+	digits := fmt.Sprintf("%d", n)
+	sign := ""
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	var b strings.Builder
+	for i, digit := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(digit)
+	}
+	return sign + b.String()
+}
+
+func formatCompact(n int) string {
+	abs := max(n, -n)
+	switch {
+	case abs >= 1_000_000:
+		return strings.Replace(fmt.Sprintf("%.1fM", float64(n)/1_000_000), ".0M", "M", 1)
+	case abs >= 1_000:
+		return strings.Replace(fmt.Sprintf("%.1fk", float64(n)/1_000), ".0k", "k", 1)
+	default:
+		return fmt.Sprintf("%d", n)
+	}
+}
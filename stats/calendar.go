@@ -0,0 +1,41 @@
+package stats
+
+import (
+	"slices"
+	"time"
+
+	"github.com/TBXark/github-status/query"
+)
+
+// CalendarDay is one day of the contribution calendar, Color is the shade
+// GitHub uses for it on the profile page.
+type CalendarDay struct {
+	Date  time.Time `json:"date"`
+	Count int       `json:"count"`
+	Color string    `json:"color"`
+}
+
+// calendarDays flattens the weeks of calendars into days ordered by date.
+// Days covered by more than one calendar are only kept once.
+func calendarDays(calendars ...query.CalendarData) ([]CalendarDay, error) {
+	byDate := make(map[time.Time]CalendarDay)
+	for _, calendar := range calendars {
+		for _, week := range calendar.Weeks {
+			for _, day := range week.ContributionDays {
+				date, err := time.Parse(time.DateOnly, day.Date)
+				if err != nil {
+					return nil, err
+				}
+				byDate[date] = CalendarDay{Date: date, Count: day.ContributionCount, Color: day.Color}
+			}
+		}
+	}
+	days := make([]CalendarDay, 0, len(byDate))
+	for _, day := range byDate {
+		days = append(days, day)
+	}
+	slices.SortFunc(days, func(a, b CalendarDay) int {
+		return a.Date.Compare(b.Date)
+	})
+	return days, nil
+}
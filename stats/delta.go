@@ -0,0 +1,41 @@
+package stats
+
+// Delta is the change between two Stats. Metrics missing from either side
+// are nil, so a failed load is not reported as a drop to zero.
+type Delta struct {
+	Stargazers    int            `json:"stargazers"`
+	Forks         int            `json:"forks"`
+	Contributions *int           `json:"contributions,omitempty"`
+	LinesChanged  *int           `json:"linesChanged,omitempty"`
+	Languages     map[string]int `json:"languages"`
+}
+
+// Diff returns the change from old to current. Languages that only appear on
+// one side count as zero on the other.
+func Diff(old, current *Stats) *Delta {
+	delta := &Delta{
+		Stargazers: current.Stargazers - old.Stargazers,
+		Forks:      current.Forks - old.Forks,
+		Languages:  make(map[string]int),
+	}
+	if old.Contributions != nil && current.Contributions != nil {
+		change := current.Contributions.TotalContributions - old.Contributions.TotalContributions
+		delta.Contributions = &change
+	}
+	if old.LineChange != nil && current.LineChange != nil {
+		change := current.LineChange.Additions + current.LineChange.Deletions - old.LineChange.Additions - old.LineChange.Deletions
+		delta.LinesChanged = &change
+	}
+	for name, lang := range current.Languages {
+		delta.Languages[name] = lang.Size
+	}
+	for name, lang := range old.Languages {
+		delta.Languages[name] -= lang.Size
+	}
+	for name, change := range delta.Languages {
+		if change == 0 {
+			delete(delta.Languages, name)
+		}
+	}
+	return delta
+}
@@ -0,0 +1,54 @@
+package stats
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	old := &Stats{
+		Stargazers:    20,
+		Forks:         5,
+		Contributions: &ContributionsStats{TotalContributions: 300},
+		LineChange:    &LineChangeStats{Additions: 1000, Deletions: 500},
+		Languages: map[string]*LanguageStats{
+			"Go":   {Size: 4000},
+			"Rust": {Size: 1000},
+			"C":    {Size: 700},
+		},
+	}
+	current := &Stats{
+		Stargazers:    18,
+		Forks:         5,
+		Contributions: &ContributionsStats{TotalContributions: 250},
+		LineChange:    &LineChangeStats{Additions: 1100, Deletions: 300},
+		Languages: map[string]*LanguageStats{
+			"Go":     {Size: 3500},
+			"C":      {Size: 700},
+			"Python": {Size: 200},
+		},
+	}
+	delta := Diff(old, current)
+	if delta.Stargazers != -2 || delta.Forks != 0 {
+		t.Errorf("Stargazers, Forks = %d, %d, want -2, 0", delta.Stargazers, delta.Forks)
+	}
+	if delta.Contributions == nil || *delta.Contributions != -50 {
+		t.Errorf("Contributions = %v, want -50", delta.Contributions)
+	}
+	if delta.LinesChanged == nil || *delta.LinesChanged != -100 {
+		t.Errorf("LinesChanged = %v, want -100", delta.LinesChanged)
+	}
+	want := map[string]int{"Go": -500, "Rust": -1000, "Python": 200}
+	if !maps.Equal(delta.Languages, want) {
+		t.Errorf("Languages = %v, want %v", delta.Languages, want)
+	}
+}
+
+func TestDiffMissingMetrics(t *testing.T) {
+	old := &Stats{Contributions: &ContributionsStats{TotalContributions: 300}}
+	current := &Stats{LineChange: &LineChangeStats{Additions: 10}}
+	delta := Diff(old, current)
+	if delta.Contributions != nil || delta.LinesChanged != nil {
+		t.Errorf("Contributions, LinesChanged = %v, %v, want nil when a side failed to load", delta.Contributions, delta.LinesChanged)
+	}
+}
@@ -0,0 +1,100 @@
+package stats
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+)
+
+// ErrorPolicy decides what GetStats does when a metric can not be loaded.
+// The failure is recorded in Stats.Errors with every policy.
+type ErrorPolicy string
+
+const (
+	// ErrorPolicyFail makes GetStats return an error once all metrics ran.
+	ErrorPolicyFail ErrorPolicy = "fail"
+	// ErrorPolicyWarn logs every failure and returns partial stats.
+	ErrorPolicyWarn ErrorPolicy = "warn"
+	// ErrorPolicyIgnore returns partial stats without logging.
+	ErrorPolicyIgnore ErrorPolicy = "ignore"
+)
+
+const (
+	MetricToken         = "token"
+	MetricLanguages     = "languages"
+	MetricViews         = "views"
+	MetricClones        = "clones"
+	MetricReferrers     = "referrers"
+	MetricPaths         = "paths"
+	MetricLinesChanged  = "linesChanged"
+	MetricContributions = "contributions"
+	MetricPinned        = "pinned"
+)
+
+func ParseErrorPolicy(s string) (ErrorPolicy, error) {
+	switch policy := ErrorPolicy(s); policy {
+	case ErrorPolicyFail, ErrorPolicyWarn, ErrorPolicyIgnore:
+		return policy, nil
+	case "":
+		return ErrorPolicyWarn, nil
+	default:
+		return "", fmt.Errorf("unknown error policy %q, use fail, warn or ignore", s)
+	}
+}
+
+func OnError(policy ErrorPolicy) Option {
+	return func(s *Loader) {
+		s.errorPolicy = policy
+	}
+}
+
+type MetricError struct {
+	Repo   string `json:"repo,omitempty"`
+	Metric string `json:"metric"`
+	Cause  string `json:"cause"`
+}
+
+func (e MetricError) Error() string {
+	if e.Repo == "" {
+		return fmt.Sprintf("%s: %s", e.Metric, e.Cause)
+	}
+	return fmt.Sprintf("%s of %s: %s", e.Metric, e.Repo, e.Cause)
+}
+
+// errorReport collects metric failures from the concurrent loaders.
+type errorReport struct {
+	policy ErrorPolicy
+	mu     sync.Mutex
+	errors []MetricError
+}
+
+func (r *errorReport) add(repo, metric string, err error) {
+	metricErr := MetricError{Repo: repo, Metric: metric, Cause: err.Error()}
+	if r.policy == ErrorPolicyWarn {
+		log.Printf("Failed to load %v", metricErr)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.errors = append(r.errors, metricErr)
+}
+
+// result returns the failures in a stable order and, with ErrorPolicyFail,
+// the error GetStats has to return.
+func (r *errorReport) result() ([]MetricError, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	slices.SortFunc(r.errors, func(a, b MetricError) int {
+		return cmp.Or(cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Metric, b.Metric))
+	})
+	if r.policy != ErrorPolicyFail || len(r.errors) == 0 {
+		return r.errors, nil
+	}
+	errs := make([]error, 0, len(r.errors))
+	for _, err := range r.errors {
+		errs = append(errs, err)
+	}
+	return r.errors, errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package stats
+
+import (
+	"cmp"
+	"slices"
+)
+
+// debugTopRepos is the length of the rankings in DebugStats.
+const debugTopRepos = 10
+
+type (
+	TopRepos struct {
+		ByLinesChanged []RepoRank `json:"byLinesChanged"`
+		ByViews        []RepoRank `json:"byViews"`
+	}
+
+	RepoRank struct {
+		Name  string `json:"name"`
+		Value int    `json:"value"`
+	}
+)
+
+// RepoMetric extracts the value a repository is ranked by, ok is false when
+// the repository has no value for it.
+type RepoMetric func(repo *RepoStats) (value int, ok bool)
+
+func RepoStargazers(repo *RepoStats) (int, bool) {
+	return repo.Stargazers, true
+}
+
+func RepoForks(repo *RepoStats) (int, bool) {
+	return repo.Forks, true
+}
+
+// RepoLinesChanged ranks by additions plus deletions of the user.
+func RepoLinesChanged(repo *RepoStats) (int, bool) {
+	if repo.LineChange == nil {
+		return 0, false
+	}
+	return repo.LineChange.Additions + repo.LineChange.Deletions, true
+}
+
+// RepoViews ranks by views over the last 14 days.
+func RepoViews(repo *RepoStats) (int, bool) {
+	if repo.Traffic == nil {
+		return 0, false
+	}
+	return repo.Traffic.Views.Count, true
+}
+
+// Listed wraps metric to leave out ignored repositories, which includes the
+// ones excluded with ExcludeRepos.
+func Listed(metric RepoMetric) RepoMetric {
+	return func(repo *RepoStats) (int, bool) {
+		if repo.Ignored {
+			return 0, false
+		}
+		return metric(repo)
+	}
+}
+
+// RankRepos returns up to limit repositories with a non-zero metric, largest
+// first.
+func RankRepos(data *Stats, metric RepoMetric, limit int) []RepoRank {
+	ranks := []RepoRank{}
+	for _, repo := range data.Repos {
+		if repo == nil {
+			continue
+		}
+		if value, ok := metric(repo); ok && value > 0 {
+			ranks = append(ranks, RepoRank{Name: repo.Name, Value: value})
+		}
+	}
+	slices.SortFunc(ranks, func(a, b RepoRank) int {
+		return cmp.Or(cmp.Compare(b.Value, a.Value), cmp.Compare(a.Name, b.Name))
+	})
+	return ranks[:min(len(ranks), limit)]
+}
@@ -2,8 +2,12 @@ package stats
 
 import (
 	"context"
+	"fmt"
+	"iter"
+	"log"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/TBXark/github-status/query"
 )
@@ -14,12 +18,46 @@ type (
 		Stargazers int    `json:"stargazers"`
 		Forks      int    `json:"forks"`
 
+		// Window is the period Contributions and LineChange cover, nil for
+		// all time.
+		Window *Window `json:"window,omitempty"`
+
 		Languages map[string]*LanguageStats `json:"languages"`
 		Repos     map[string]*RepoStats     `json:"repos"`
 
 		Contributions *ContributionsStats `json:"contributions"`
 		LineChange    *LineChangeStats    `json:"lineChange"`
 		Views         *ViewStats          `json:"views"`
+		Clones        *CloneStats         `json:"clones"`
+
+		// Pinned are the repositories pinned to the profile, only name,
+		// description, language, stars and forks are set.
+		Pinned []*RepoStats `json:"pinned"`
+
+		// Calendar is the daily contribution count, oldest first.
+		Calendar []CalendarDay `json:"calendar"`
+		Streaks  *StreakStats  `json:"streaks"`
+
+		// Referrers and Paths are the popular referrers and pages of all
+		// repositories over the last 14 days, most visited first.
+		Referrers []*ReferrerStats `json:"referrers"`
+		Paths     []*PathStats     `json:"paths"`
+
+		// Errors lists every metric that could not be loaded, the numbers
+		// above are incomplete when it is not empty.
+		Errors []MetricError `json:"errors,omitempty"`
+		Debug  DebugStats    `json:"debug"`
+	}
+
+	DebugStats struct {
+		// LanguageBytesRecovered counts the bytes of languages beyond the
+		// first page of a repository, which used to be dropped.
+		LanguageBytesRecovered int `json:"languageBytesRecovered"`
+		LanguagePagesFetched   int `json:"languagePagesFetched"`
+
+		// TopRepos shows which repositories contribute most to the lines
+		// changed and views.
+		TopRepos TopRepos `json:"topRepos"`
 	}
 
 	ContributionsStats struct {
@@ -33,10 +71,28 @@ type (
 	LineChangeStats struct {
 		Additions int `json:"additions"`
 		Deletions int `json:"deletions"`
+		Commits   int `json:"commits"`
 	}
 
 	ViewStats struct {
-		Count int `json:"count"`
+		// Count and Uniques cover the 14 days GitHub reports.
+		Count   int `json:"count"`
+		Uniques int `json:"uniques"`
+
+		// The accumulated totals include the days kept by the TrafficStore.
+		AllTime    TrafficTotal `json:"allTime"`
+		Last30Days TrafficTotal `json:"last30Days"`
+		Last90Days TrafficTotal `json:"last90Days"`
+		Since      time.Time    `json:"since"`
+	}
+
+	// CloneStats has the same shape as ViewStats, built from repository
+	// clones.
+	CloneStats = ViewStats
+
+	RepoLanguage struct {
+		Name  string `json:"name"`
+		Color string `json:"color"`
 	}
 
 	LanguageStats struct {
@@ -48,11 +104,20 @@ type (
 	}
 
 	RepoStats struct {
-		Name       string         `json:"name"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		// Language is the primary language, nil when GitHub detected none.
+		Language *RepoLanguage `json:"language,omitempty"`
+
 		Forks      int            `json:"forks"`
 		Stargazers int            `json:"stargazers"`
 		Languages  map[string]int `json:"languages"`
 		Ignored    bool           `json:"ignored"`
+
+		// LineChange and Traffic are nil when they were ignored or could not
+		// be loaded for this repository.
+		LineChange *LineChangeStats  `json:"lineChange,omitempty"`
+		Traffic    *RepoTrafficStats `json:"traffic,omitempty"`
 	}
 
 	Filter struct {
@@ -63,6 +128,7 @@ type (
 
 		ignoreLinesChanged bool
 		ignoreRepoViews    bool
+		ignorePinnedRepos  bool
 
 		excludeRepos map[string]struct{}
 		excludeLangs map[string]struct{}
@@ -71,29 +137,62 @@ type (
 )
 
 type Loader struct {
-	username string
-	filter   *Filter
-	queries  *query.Queries
+	username     string
+	filter       *Filter
+	queries      *query.Queries
+	queryOptions []query.Option
+	errorPolicy  ErrorPolicy
+	trafficStore TrafficStore
+	window       Window
+	now          func() time.Time
+	tokenInfo    *query.TokenInfo
+
+	streakLocation     *time.Location
+	streakSkipWeekends bool
 }
 
 type Option func(*Loader)
 
 func NewStats(username, accessToken string, options ...Option) *Loader {
 	s := &Loader{
-		username: username,
+		username:    username,
+		errorPolicy: ErrorPolicyWarn,
+		now:         time.Now,
 		filter: &Filter{
 			excludeRepos: make(map[string]struct{}),
 			excludeLangs: make(map[string]struct{}),
 			includeOwner: make(map[string]struct{}),
 		},
-		queries: query.NewQueries(accessToken),
 	}
 	for _, option := range options {
 		option(s)
 	}
+	s.queries = query.NewQueries(accessToken, s.queryOptions...)
 	return s
 }
 
+// WithClock replaces time.Now, so runs that depend on the current time, such
+// as windows that end today, can be replayed from fixtures.
+func WithClock(now func() time.Time) Option {
+	return func(s *Loader) {
+		s.now = now
+	}
+}
+
+// WithTokenInfo passes the TokenInfo of the access token when it was already
+// inspected, GetStats then skips the request.
+func WithTokenInfo(info *query.TokenInfo) Option {
+	return func(s *Loader) {
+		s.tokenInfo = info
+	}
+}
+
+func QueryOptions(options ...query.Option) Option {
+	return func(s *Loader) {
+		s.queryOptions = append(s.queryOptions, options...)
+	}
+}
+
 func IgnoreForkedRepos(flag bool) Option {
 	return func(s *Loader) {
 		s.filter.ignoreForkedRepos = flag
@@ -130,6 +229,13 @@ func IgnoreRepoViews(flag bool) Option {
 	}
 }
 
+// IgnorePinnedRepos skips the pinned repositories, Stats.Pinned stays nil.
+func IgnorePinnedRepos(flag bool) Option {
+	return func(s *Loader) {
+		s.filter.ignorePinnedRepos = flag
+	}
+}
+
 func ExcludeRepos(repos ...string) Option {
 	return func(s *Loader) {
 		for _, repo := range repos {
@@ -160,79 +266,85 @@ func (s *Loader) GetStats(ctx context.Context) (*Stats, error) {
 		Languages: make(map[string]*LanguageStats),
 		Repos:     make(map[string]*RepoStats),
 	}
+	if !s.window.IsZero() {
+		stats.Window = &s.window
+	}
+
+	report := &errorReport{policy: s.errorPolicy}
+
+	info := s.tokenInfo
+	if info == nil {
+		var err error
+		if info, err = s.queries.TokenInfo(ctx); err != nil {
+			report.add("", MetricToken, fmt.Errorf("assuming full access: %w", err))
+		}
+	}
+	filter := s.filterForToken(info)
 
 	var reqGroup sync.WaitGroup
 	var readGroup sync.WaitGroup
 
-	viewChan := make(chan int)
-	linesChan := make(chan [2]int)
+	trafficChan := make(chan repoTraffic)
+	linesChan := make(chan repoLines)
 	semaphore := make(chan struct{}, 60)
 
-	if !s.filter.ignoreRepoViews {
+	var traffic *trafficCollector
+	var trafficLoaded bool
+	if !filter.ignoreRepoViews {
+		var history map[string]*TrafficHistory
+		history, trafficLoaded = s.loadTraffic(report)
+		traffic = newTrafficCollector(history)
 		readGroup.Add(1)
-		stats.Views = &ViewStats{}
-		go func(r *Stats) {
+		go func() {
 			defer readGroup.Done()
-			for views := range viewChan {
-				r.Views.Count += views
+			for repoTraffic := range trafficChan {
+				traffic.add(repoTraffic)
 			}
-		}(stats)
+		}()
 	}
 
-	if !s.filter.ignoreLinesChanged {
+	repoLineChanges := make(map[string]*LineChangeStats)
+	if !filter.ignoreLinesChanged {
 		readGroup.Add(1)
 		stats.LineChange = &LineChangeStats{}
 		go func(r *Stats) {
 			defer readGroup.Done()
 			for lines := range linesChan {
-				r.LineChange.Additions += lines[0]
-				r.LineChange.Deletions += lines[1]
+				r.LineChange.Additions += lines.Additions
+				r.LineChange.Deletions += lines.Deletions
+				r.LineChange.Commits += lines.Commits
+				repoLineChanges[lines.repo] = &lines.LineChangeStats
 			}
 		}(stats)
 	}
 
-	var queries []func(ctx context.Context, login, after string) (*query.RepositoriesPage, error)
-
-	queries = append(queries, s.queries.Repositories)
-	if !s.filter.ignoreContributedTo {
-		queries = append(queries, s.queries.RepositoriesContributedTo)
-	}
-
-	for _, q := range queries {
-		after := ""
-		for {
-			repositories, err := q(ctx, s.username, after)
-			if err != nil {
-				return nil, err
+	for repo, err := range s.Repositories(ctx) {
+		if err != nil {
+			return nil, err
+		}
+		repoStat := s.mergeRepoToStats(ctx, filter, &repo, stats, report)
+		if repoStat == nil {
+			continue
+		}
+		reqGroup.Add(1)
+		go func(repo string, canReadTraffic bool) {
+			semaphore <- struct{}{}
+			defer func() {
+				<-semaphore
+				reqGroup.Done()
+			}()
+			if !filter.ignoreRepoViews && canReadTraffic {
+				// A failed repository still counts with its stored days.
+				trafficChan <- s.traffic(ctx, repo, report)
 			}
-			for _, repo := range repositories.Nodes {
-				if s.mergeRepoToStats(&repo, stats) == nil {
-					continue
+			if !filter.ignoreLinesChanged {
+				if lines, e := s.linesChanged(ctx, repo); e == nil {
+					linesChan <- lines
+				} else {
+					report.add(repo, MetricLinesChanged, e)
 				}
-				reqGroup.Add(1)
-				go func(repo string) {
-					semaphore <- struct{}{}
-					defer func() {
-						<-semaphore
-						reqGroup.Done()
-					}()
-					if !s.filter.ignoreRepoViews {
-						if views, e := s.views(ctx, repo); e == nil {
-							viewChan <- views
-						}
-					}
-					if !s.filter.ignoreLinesChanged {
-						if lines, e := s.linesChanged(ctx, repo); e == nil {
-							linesChan <- lines
-						}
-					}
-				}(repo.NameWithOwner)
-			}
-			if !repositories.PageInfo.HasNextPage {
-				break
 			}
-			after = repositories.PageInfo.EndCursor
-		}
+		}(repo.NameWithOwner, !repoStat.Ignored && s.canReadTraffic(filter, &repo))
 	}
 
 	var totalSize int
@@ -246,58 +358,154 @@ func (s *Loader) GetStats(ctx context.Context) (*Stats, error) {
 		}
 	}
 
-	if totalContributions, e := s.totalContributions(ctx); e == nil {
+	if !filter.ignorePinnedRepos {
+		if pinned, e := s.pinnedRepos(ctx); e == nil {
+			stats.Pinned = pinned
+		} else {
+			report.add("", MetricPinned, e)
+		}
+	}
+
+	if totalContributions, calendar, e := s.totalContributions(ctx); e == nil {
 		stats.Contributions = totalContributions
+		stats.Calendar = calendar
+		stats.Streaks = computeStreaks(calendar, s.today(), s.streakSkipWeekends)
+	} else {
+		report.add("", MetricContributions, e)
 	}
 
 	reqGroup.Wait()
-	close(viewChan)
+	close(trafficChan)
 	close(linesChan)
 	readGroup.Wait()
 
+	for repo, lines := range repoLineChanges {
+		if repoStats := stats.Repos[repo]; repoStats != nil {
+			repoStats.LineChange = lines
+		}
+	}
+	if traffic != nil {
+		traffic.finish(stats, s.now())
+		if trafficLoaded {
+			s.saveTraffic(traffic.history, report)
+		}
+	}
+	stats.Debug.TopRepos = TopRepos{
+		ByLinesChanged: RankRepos(stats, RepoLinesChanged, debugTopRepos),
+		ByViews:        RankRepos(stats, RepoViews, debugTopRepos),
+	}
+
+	errs, err := report.result()
+	stats.Errors = errs
+	if err != nil {
+		return nil, err
+	}
 	return stats, nil
 }
 
-func (s *Loader) mergeRepoToStats(repo *query.Repository, stats *Stats) *RepoStats {
+// Repositories lazily walks the repositories of the user followed by the ones
+// they contributed to, unless IgnoreContributedToRepos is set. Repositories
+// are yielded unfiltered and may appear in both connections.
+func (s *Loader) Repositories(ctx context.Context) iter.Seq2[query.Repository, error] {
+	return func(yield func(query.Repository, error) bool) {
+		for repo, err := range s.queries.AllRepositories(ctx, s.username) {
+			if !yield(repo, err) || err != nil {
+				return
+			}
+		}
+		if s.filter.ignoreContributedTo {
+			return
+		}
+		for repo, err := range s.queries.AllRepositoriesContributedTo(ctx, s.username) {
+			if !yield(repo, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// filterForToken returns the filter of one GetStats call, with the features
+// the token has no access to turned off, so they are skipped with a log line
+// instead of silently reporting zeros. A nil info assumes full access.
+func (s *Loader) filterForToken(info *query.TokenInfo) *Filter {
+	filter := *s.filter
+	if info == nil {
+		return &filter
+	}
+	if !filter.ignorePrivateRepos && !info.CanReadPrivateRepos() {
+		log.Printf("Skipping private repositories: %s token has no repo scope", info.Type)
+		filter.ignorePrivateRepos = true
+	}
+	if !filter.ignoreRepoViews && !info.CanReadTraffic() {
+		log.Printf("Skipping repository views: %s token has neither the repo nor the public_repo scope", info.Type)
+		filter.ignoreRepoViews = true
+	}
+	return &filter
+}
+
+// canReadTraffic reports whether the traffic API is available for repo, it
+// requires push access.
+func (s *Loader) canReadTraffic(filter *Filter, repo *query.Repository) bool {
+	if filter.ignoreRepoViews {
+		return false
+	}
+	switch repo.ViewerPermission {
+	case "ADMIN", "MAINTAIN", "WRITE", "":
+		return true
+	default:
+		log.Printf("Skipping views for %s: token has %s permission, push access is required", repo.NameWithOwner, repo.ViewerPermission)
+		return false
+	}
+}
+
+func (s *Loader) mergeRepoToStats(ctx context.Context, filter *Filter, repo *query.Repository, stats *Stats, report *errorReport) *RepoStats {
 	if _, ok := stats.Repos[repo.NameWithOwner]; ok {
 		return nil
 	}
 
 	owner := strings.Split(repo.NameWithOwner, "/")[0]
-	if _, ok := s.filter.includeOwner[strings.ToLower(owner)]; !ok {
+	if _, ok := filter.includeOwner[strings.ToLower(owner)]; !ok {
 		stats.Repos[repo.NameWithOwner] = nil
 		return nil
 	}
 
 	repoStat := &RepoStats{
-		Name:       repo.NameWithOwner,
-		Forks:      repo.ForkCount,
-		Stargazers: repo.Stargazers.TotalCount,
-		Languages:  make(map[string]int),
-		Ignored:    true,
+		Name:        repo.NameWithOwner,
+		Description: repo.Description,
+		Forks:       repo.ForkCount,
+		Stargazers:  repo.Stargazers.TotalCount,
+		Languages:   make(map[string]int),
+		Ignored:     true,
+	}
+	if repo.PrimaryLanguage != nil {
+		repoStat.Language = &RepoLanguage{Name: repo.PrimaryLanguage.Name, Color: repo.PrimaryLanguage.Color}
 	}
 
 	stats.Stargazers += repo.Stargazers.TotalCount
 	stats.Forks += repo.ForkCount
 	stats.Repos[repo.NameWithOwner] = repoStat
 
-	if _, ok := s.filter.excludeRepos[strings.ToLower(repo.NameWithOwner)]; ok {
+	if _, ok := filter.excludeRepos[strings.ToLower(repo.NameWithOwner)]; ok {
 		return repoStat
 	}
 
-	if s.filter.ignoreForkedRepos && repo.IsFork {
+	if filter.ignoreForkedRepos && repo.IsFork {
 		return repoStat
 	}
-	if s.filter.ignoreArchivedRepos && repo.IsArchived {
+	if filter.ignoreArchivedRepos && repo.IsArchived {
 		return repoStat
 	}
-	if s.filter.ignorePrivateRepos && repo.IsPrivate {
+	if filter.ignorePrivateRepos && repo.IsPrivate {
 		return repoStat
 	}
 
+	if repo.Languages.PageInfo.HasNextPage {
+		s.fetchRemainingLanguages(ctx, repo, stats, report)
+	}
+
 	for _, lang := range repo.Languages.Edges {
 		repoStat.Languages[lang.Node.Name] = lang.Size
-		if _, ok := s.filter.excludeLangs[strings.ToLower(lang.Node.Name)]; ok {
+		if _, ok := filter.excludeLangs[strings.ToLower(lang.Node.Name)]; ok {
 			continue
 		}
 		if stats.Languages[lang.Node.Name] == nil {
@@ -313,10 +521,33 @@ func (s *Loader) mergeRepoToStats(repo *query.Repository, stats *Stats) *RepoSta
 	return repoStat
 }
 
-func (s *Loader) totalContributions(ctx context.Context) (*ContributionsStats, error) {
+// fetchRemainingLanguages pages through the languages the repositories query
+// cut off and appends them to repo.
+func (s *Loader) fetchRemainingLanguages(ctx context.Context, repo *query.Repository, stats *Stats, report *errorReport) {
+	pageInfo := repo.Languages.PageInfo
+	for pageInfo.HasNextPage {
+		page, err := s.queries.RepositoryLanguages(ctx, repo.NameWithOwner, pageInfo.EndCursor)
+		if err != nil {
+			report.add(repo.NameWithOwner, MetricLanguages, err)
+			return
+		}
+		stats.Debug.LanguagePagesFetched++
+		for _, edge := range page.Edges {
+			stats.Debug.LanguageBytesRecovered += edge.Size
+		}
+		repo.Languages.Edges = append(repo.Languages.Edges, page.Edges...)
+		pageInfo = page.PageInfo
+	}
+	repo.Languages.PageInfo = pageInfo
+}
+
+func (s *Loader) totalContributions(ctx context.Context) (*ContributionsStats, []CalendarDay, error) {
+	if !s.window.IsZero() {
+		return s.windowContributions(ctx)
+	}
 	con, err := s.queries.ContributionsCollection(ctx, s.username)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	stats := &ContributionsStats{
 		TotalContributions:                  0,
@@ -327,41 +558,107 @@ func (s *Loader) totalContributions(ctx context.Context) (*ContributionsStats, e
 	}
 	allContrib, err := s.queries.AllContribYears(ctx, s.username, con.ContributionsCollection.ContributionYears)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	calendars := make([]query.CalendarData, 0, len(allContrib))
 	for _, year := range allContrib {
 		stats.TotalContributions += year.ContributionCalendar.TotalContributions
+		calendars = append(calendars, year.ContributionCalendar)
 	}
-	return stats, nil
+	calendar, err := calendarDays(calendars...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return stats, calendar, nil
+}
+
+func (s *Loader) pinnedRepos(ctx context.Context) ([]*RepoStats, error) {
+	items, err := s.queries.PinnedItems(ctx, s.username)
+	if err != nil {
+		return nil, err
+	}
+	pinned := make([]*RepoStats, 0, len(items))
+	for _, item := range items {
+		repo := &RepoStats{
+			Name:        item.NameWithOwner,
+			Description: item.Description,
+			Stargazers:  item.StargazerCount,
+			Forks:       item.ForkCount,
+		}
+		if item.PrimaryLanguage != nil {
+			repo.Language = &RepoLanguage{Name: item.PrimaryLanguage.Name, Color: item.PrimaryLanguage.Color}
+		}
+		pinned = append(pinned, repo)
+	}
+	return pinned, nil
+}
+
+// today is the current calendar day in the streak location, as a UTC date
+// like the days of the calendar.
+func (s *Loader) today() time.Time {
+	loc := s.streakLocation
+	if loc == nil {
+		loc = time.UTC
+	}
+	year, month, day := s.now().In(loc).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// windowContributions counts the contributions inside the window with a
+// single contributionsCollection, which GitHub limits to one year.
+func (s *Loader) windowContributions(ctx context.Context) (*ContributionsStats, []CalendarDay, error) {
+	if s.window.Since.IsZero() {
+		return nil, nil, fmt.Errorf("window %q has no start date", s.window.Label)
+	}
+	to := s.now()
+	if !s.window.Until.IsZero() && s.window.Until.Before(to) {
+		to = s.window.Until
+	}
+	if to.After(s.window.Since.AddDate(1, 0, 0)) {
+		return nil, nil, fmt.Errorf("window %q is longer than one year", s.window.Label)
+	}
+	con, err := s.queries.ContributionsWindow(ctx, s.username, s.window.Since, to)
+	if err != nil {
+		return nil, nil, err
+	}
+	calendar, err := calendarDays(con.ContributionsCollection.ContributionCalendar)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &ContributionsStats{
+		TotalContributions:                  con.ContributionsCollection.ContributionCalendar.TotalContributions,
+		TotalCommitContributions:            con.ContributionsCollection.TotalCommitContributions,
+		TotalIssueContributions:             con.ContributionsCollection.TotalIssueContributions,
+		TotalPullRequestContributions:       con.ContributionsCollection.TotalPullRequestContributions,
+		TotalPullRequestReviewContributions: con.ContributionsCollection.TotalPullRequestReviewContributions,
+	}, calendar, nil
 }
 
-func (s *Loader) linesChanged(ctx context.Context, repo string) ([2]int, error) {
+type repoLines struct {
+	repo string
+	LineChangeStats
+}
+
+func (s *Loader) linesChanged(ctx context.Context, repo string) (repoLines, error) {
 	username := strings.ToLower(s.username)
-	additions, deletions := 0, 0
+	lines := repoLines{repo: repo}
 	con, err := s.queries.RepoContributors(ctx, repo)
 	if err != nil {
-		return [2]int{0, 0}, err
+		return lines, err
 	}
 	for _, contributor := range *con {
 		if strings.ToLower(contributor.Author.Login) != username {
 			continue
 		}
 		for _, week := range contributor.Weeks {
-			additions += week.A
-			deletions += week.D
+			// Weeks are counted when they start inside the window.
+			if !s.window.Contains(time.Unix(int64(week.W), 0)) {
+				continue
+			}
+			lines.Additions += week.A
+			lines.Deletions += week.D
+			lines.Commits += week.C
 		}
 	}
-	return [2]int{additions, deletions}, nil
-}
-
-func (s *Loader) views(ctx context.Context, repo string) (int, error) {
-	total := 0
-	traffic, err := s.queries.RepoTraffic(ctx, repo)
-	if err != nil {
-		return 0, err
-	}
-	for _, view := range traffic.Views {
-		total += view.Count
-	}
-	return total, nil
+	return lines, nil
 }
@@ -0,0 +1,163 @@
+package stats
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/TBXark/github-status/query"
+)
+
+// replayClock is the time the fixtures in testdata/replay were recorded at.
+func replayClock() time.Time {
+	return time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+}
+
+// replayStats loads the stats of bob from the recorded fixtures, any request
+// without a fixture fails the run.
+func replayStats(t *testing.T, options ...Option) *Stats {
+	t.Helper()
+	options = append([]Option{
+		OnError(ErrorPolicyFail),
+		IncludeOwner("bob"),
+		WithClock(replayClock),
+		QueryOptions(
+			query.WithTransport(&query.ReplayTransport{Dir: "testdata/replay"}),
+			query.WithEndpoints("https://github.example.com", ""),
+		),
+	}, options...)
+	data, err := NewStats("bob", "ghp_replay", options...).GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	return data
+}
+
+func TestGetStatsReplay(t *testing.T) {
+	data := replayStats(t)
+	if data.Name != "bob" || data.Stargazers != 13 || data.Forks != 3 {
+		t.Errorf("name, stars, forks = %s, %d, %d, want bob, 13, 3", data.Name, data.Stargazers, data.Forks)
+	}
+	if data.Window != nil {
+		t.Errorf("Window = %+v, want nil", data.Window)
+	}
+	if len(data.Languages) != 3 || data.Languages["Go"] == nil || data.Languages["Go"].Size != 1000 {
+		t.Errorf("Languages = %v, want Go, Shell and Zig with 1000 bytes of Go", data.Languages)
+	}
+	if data.Debug.LanguageBytesRecovered != 77 {
+		t.Errorf("LanguageBytesRecovered = %d, want 77", data.Debug.LanguageBytesRecovered)
+	}
+	if data.Contributions == nil || data.Contributions.TotalContributions != 200 {
+		t.Errorf("Contributions = %+v, want 200 in total", data.Contributions)
+	}
+	if want := (LineChangeStats{Additions: 2200, Deletions: 1040, Commits: 10}); data.LineChange == nil || *data.LineChange != want {
+		t.Errorf("LineChange = %+v, want %+v", data.LineChange, want)
+	}
+	if data.Views == nil || data.Views.Count != 462 || data.Views.Uniques != 82 {
+		t.Errorf("Views = %+v, want 462 views by 82 visitors", data.Views)
+	}
+	if len(data.Referrers) == 0 || data.Referrers[0].Referrer != "google.com" {
+		t.Errorf("Referrers = %v, want google.com first", data.Referrers)
+	}
+	if len(data.Pinned) != 1 || data.Pinned[0].Name != "bob/alpha" {
+		t.Errorf("Pinned = %v, want bob/alpha", data.Pinned)
+	}
+	if len(data.Calendar) == 0 || !data.Calendar[len(data.Calendar)-1].Date.Equal(time.Date(2026, 10, 18, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Calendar does not end on the day of the recording")
+	}
+	if data.Streaks == nil || data.Streaks.Current.Length != 6 || data.Streaks.ActiveDays != 50 {
+		t.Errorf("Streaks = %+v, want a current streak of 6 and 50 active days", data.Streaks)
+	}
+	if len(data.Errors) != 0 {
+		t.Errorf("Errors = %v", data.Errors)
+	}
+}
+
+func TestGetStatsReplayWindow(t *testing.T) {
+	window := NewWindow(time.Date(2026, 7, 1, 0, 0, 0, 0, time.UTC), time.Time{})
+	data := replayStats(t, WithWindow(window))
+	if data.Window == nil || data.Window.Label != "since 2026-07-01" {
+		t.Errorf("Window = %+v, want since 2026-07-01", data.Window)
+	}
+	if data.Contributions == nil || data.Contributions.TotalContributions != 42 {
+		t.Errorf("Contributions = %+v, want 42 in total", data.Contributions)
+	}
+	if want := (LineChangeStats{Additions: 200, Deletions: 40, Commits: 6}); data.LineChange == nil || *data.LineChange != want {
+		t.Errorf("LineChange = %+v, want %+v", data.LineChange, want)
+	}
+}
+
+type pathRecorder struct {
+	next  http.RoundTripper
+	paths []string
+}
+
+func (p *pathRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	p.paths = append(p.paths, req.URL.Path)
+	return p.next.RoundTrip(req)
+}
+
+func TestGetStatsTokenInfo(t *testing.T) {
+	transport := &pathRecorder{next: &query.ReplayTransport{Dir: "testdata/replay"}}
+	info := &query.TokenInfo{Type: query.TokenTypeClassic, Scopes: []string{"read:user"}}
+	loader := NewStats("bob", "ghp_replay",
+		OnError(ErrorPolicyFail),
+		IncludeOwner("bob"),
+		WithClock(replayClock),
+		WithTokenInfo(info),
+		QueryOptions(
+			query.WithTransport(transport),
+			query.WithEndpoints("https://github.example.com", ""),
+		),
+	)
+	data, err := loader.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if slices.Contains(transport.paths, "/api/v3/user") {
+		t.Error("GetStats requested /user although the TokenInfo was passed")
+	}
+	if data.Views != nil {
+		t.Error("views were loaded without the repo or public_repo scope")
+	}
+	// The degraded features only apply to this call.
+	if loader.filter.ignorePrivateRepos || loader.filter.ignoreRepoViews {
+		t.Errorf("GetStats changed the loader filter to %+v", loader.filter)
+	}
+	if filter := loader.filterForToken(&query.TokenInfo{Scopes: []string{"repo"}}); filter.ignorePrivateRepos || filter.ignoreRepoViews {
+		t.Errorf("filter for a repo scoped token = %+v, want full access", filter)
+	}
+}
+
+// failPinned fails the pinned repositories query and replays the rest.
+type failPinned struct {
+	next http.RoundTripper
+}
+
+func (f failPinned) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		body, err := io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		if bytes.Contains(body, []byte("pinnedItems")) {
+			return nil, errors.New("pinned repositories requested")
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	return f.next.RoundTrip(req)
+}
+
+func TestGetStatsIgnorePinnedRepos(t *testing.T) {
+	// With ErrorPolicyFail the run fails if the pinned query is sent.
+	transport := failPinned{next: &query.ReplayTransport{Dir: "testdata/replay"}}
+	data := replayStats(t, IgnorePinnedRepos(true), QueryOptions(query.WithTransport(transport)))
+	if data.Pinned != nil {
+		t.Errorf("Pinned = %v, want nil", data.Pinned)
+	}
+}
@@ -0,0 +1,79 @@
+package stats
+
+import (
+	"time"
+)
+
+type (
+	StreakStats struct {
+		Current Streak `json:"current"`
+		Longest Streak `json:"longest"`
+		// ActiveDays counts the days of the calendar with contributions.
+		ActiveDays int `json:"activeDays"`
+	}
+
+	// Streak is a run of days with contributions, Start and End are zero
+	// when Length is.
+	Streak struct {
+		Length int       `json:"length"`
+		Start  time.Time `json:"start"`
+		End    time.Time `json:"end"`
+	}
+)
+
+// StreakLocation sets the timezone that decides which calendar day is today,
+// UTC by default.
+func StreakLocation(loc *time.Location) Option {
+	return func(s *Loader) {
+		s.streakLocation = loc
+	}
+}
+
+// StreakSkipWeekends keeps streaks alive over Saturdays and Sundays without
+// contributions. Weekend days with contributions still count.
+func StreakSkipWeekends(flag bool) Option {
+	return func(s *Loader) {
+		s.streakSkipWeekends = flag
+	}
+}
+
+// computeStreaks walks calendar up to today. A day without contributions
+// breaks a streak unless it is today, which may still get some, or a skipped
+// weekend day. Days missing from the calendar break it as well.
+func computeStreaks(calendar []CalendarDay, today time.Time, skipWeekends bool) *StreakStats {
+	streaks := &StreakStats{}
+	skippable := func(day time.Time) bool {
+		weekday := day.Weekday()
+		return day.Equal(today) || skipWeekends && (weekday == time.Saturday || weekday == time.Sunday)
+	}
+	var run Streak
+	var previous time.Time
+	for _, day := range calendar {
+		if day.Date.After(today) {
+			break
+		}
+		if !previous.IsZero() && !day.Date.Equal(previous.AddDate(0, 0, 1)) {
+			run = Streak{}
+		}
+		previous = day.Date
+		switch {
+		case day.Count > 0:
+			streaks.ActiveDays++
+			if run.Length == 0 {
+				run.Start = day.Date
+			}
+			run.Length++
+			run.End = day.Date
+			if run.Length > streaks.Longest.Length {
+				streaks.Longest = run
+			}
+		case !skippable(day.Date):
+			run = Streak{}
+		}
+	}
+	// The run left over is current if nothing broke it until today.
+	if run.Length > 0 && !previous.Before(today.AddDate(0, 0, -1)) {
+		streaks.Current = run
+	}
+	return streaks
+}
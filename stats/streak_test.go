@@ -0,0 +1,145 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+// days builds a calendar of consecutive days from start, a Monday.
+func days(start string, counts ...int) []CalendarDay {
+	date, _ := time.Parse(time.DateOnly, start)
+	calendar := make([]CalendarDay, len(counts))
+	for i, count := range counts {
+		calendar[i] = CalendarDay{Date: date.AddDate(0, 0, i), Count: count}
+	}
+	return calendar
+}
+
+func date(s string) time.Time {
+	d, _ := time.Parse(time.DateOnly, s)
+	return d
+}
+
+func TestComputeStreaks(t *testing.T) {
+	missing := append(days("2026-10-05", 1, 1), days("2026-10-08", 1, 1)...)
+	tests := []struct {
+		name         string
+		calendar     []CalendarDay
+		today        string
+		skipWeekends bool
+		current      Streak
+		longest      int
+		active       int
+	}{
+		{
+			name:     "gap breaks a streak",
+			calendar: days("2026-10-05", 1, 1, 0, 1, 1, 1),
+			today:    "2026-10-10",
+			current:  Streak{Length: 3, Start: date("2026-10-08"), End: date("2026-10-10")},
+			longest:  3, active: 5,
+		},
+		{
+			name:     "today without contributions keeps the current streak",
+			calendar: days("2026-10-05", 1, 1, 1, 0),
+			today:    "2026-10-08",
+			current:  Streak{Length: 3, Start: date("2026-10-05"), End: date("2026-10-07")},
+			longest:  3, active: 3,
+		},
+		{
+			name:     "yesterday without contributions ends the current streak",
+			calendar: days("2026-10-05", 1, 1, 0, 0),
+			today:    "2026-10-08",
+			longest:  2, active: 2,
+		},
+		{
+			name:     "days after today are ignored",
+			calendar: days("2026-10-05", 1, 1, 1, 1),
+			today:    "2026-10-06",
+			current:  Streak{Length: 2, Start: date("2026-10-05"), End: date("2026-10-06")},
+			longest:  2, active: 2,
+		},
+		{
+			name:         "empty weekend skipped",
+			calendar:     days("2026-10-05", 1, 1, 1, 1, 1, 0, 0, 1),
+			today:        "2026-10-12",
+			skipWeekends: true,
+			current:      Streak{Length: 6, Start: date("2026-10-05"), End: date("2026-10-12")},
+			longest:      6, active: 6,
+		},
+		{
+			name:     "empty weekend breaks without skipping",
+			calendar: days("2026-10-05", 1, 1, 1, 1, 1, 0, 0, 1),
+			today:    "2026-10-12",
+			current:  Streak{Length: 1, Start: date("2026-10-12"), End: date("2026-10-12")},
+			longest:  5, active: 6,
+		},
+		{
+			name:         "skipped weekend does not extend a streak",
+			calendar:     days("2026-10-05", 0, 0, 0, 0, 1, 0, 0),
+			today:        "2026-10-11",
+			skipWeekends: true,
+			current:      Streak{Length: 1, Start: date("2026-10-09"), End: date("2026-10-09")},
+			longest:      1, active: 1,
+		},
+		{
+			name:         "weekend contributions count when skipping",
+			calendar:     days("2026-10-05", 0, 0, 0, 0, 1, 1, 1, 1),
+			today:        "2026-10-12",
+			skipWeekends: true,
+			current:      Streak{Length: 4, Start: date("2026-10-09"), End: date("2026-10-12")},
+			longest:      4, active: 4,
+		},
+		{
+			name:     "missing day breaks a streak",
+			calendar: missing,
+			today:    "2026-10-09",
+			current:  Streak{Length: 2, Start: date("2026-10-08"), End: date("2026-10-09")},
+			longest:  2, active: 4,
+		},
+		{
+			name:     "calendar ending before yesterday has no current streak",
+			calendar: days("2026-10-05", 1, 1, 1),
+			today:    "2026-10-09",
+			longest:  3, active: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streaks := computeStreaks(tt.calendar, date(tt.today), tt.skipWeekends)
+			if streaks.Current != tt.current {
+				t.Errorf("Current = %+v, want %+v", streaks.Current, tt.current)
+			}
+			if streaks.Longest.Length != tt.longest || streaks.ActiveDays != tt.active {
+				t.Errorf("Longest = %d, ActiveDays = %d, want %d, %d", streaks.Longest.Length, streaks.ActiveDays, tt.longest, tt.active)
+			}
+		})
+	}
+}
+
+func TestStreakTodayInLocation(t *testing.T) {
+	// 03:00 UTC on Sunday is still Saturday evening in UTC-7.
+	now := time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC)
+	calendar := days("2026-10-16", 1, 0, 0)
+	tests := []struct {
+		name    string
+		loc     *time.Location
+		today   string
+		current int
+	}{
+		{"UTC", nil, "2026-10-18", 0},
+		{"behind UTC", time.FixedZone("UTC-7", -7*3600), "2026-10-17", 1},
+		{"ahead of UTC", time.FixedZone("UTC+9", 9*3600), "2026-10-18", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loader := NewStats("bob", "", WithClock(func() time.Time { return now }), StreakLocation(tt.loc))
+			today := loader.today()
+			if !today.Equal(date(tt.today)) || today.Location() != time.UTC {
+				t.Fatalf("today = %s, want %s UTC", today, tt.today)
+			}
+			if streaks := computeStreaks(calendar, today, false); streaks.Current.Length != tt.current {
+				t.Errorf("Current = %+v, want length %d", streaks.Current, tt.current)
+			}
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package stats
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"time"
+)
+
+// TrafficStore persists daily repository traffic, GitHub only reports the
+// last 14 days so older days are only known from earlier runs.
+type TrafficStore interface {
+	LoadTraffic() (map[string]*TrafficHistory, error)
+	SaveTraffic(map[string]*TrafficHistory) error
+}
+
+func WithTrafficStore(store TrafficStore) Option {
+	return func(s *Loader) {
+		s.trafficStore = store
+	}
+}
+
+type (
+	// TrafficHistory is every known day of traffic of one repository.
+	TrafficHistory struct {
+		Views  []TrafficDay `json:"views"`
+		Clones []TrafficDay `json:"clones,omitempty"`
+	}
+
+	TrafficDay struct {
+		Date    time.Time `json:"date"`
+		Count   int       `json:"count"`
+		Uniques int       `json:"uniques"`
+	}
+
+	// TrafficTotal sums the daily counts of a period. Uniques is the sum of
+	// daily unique visitors, a visitor returning on another day counts again.
+	TrafficTotal struct {
+		Count   int `json:"count"`
+		Uniques int `json:"uniques"`
+	}
+
+	// RepoTrafficStats is the traffic of one repository over the 14 days
+	// GitHub reports.
+	RepoTrafficStats struct {
+		Views     TrafficTotal     `json:"views"`
+		Clones    TrafficTotal     `json:"clones"`
+		Referrers []*ReferrerStats `json:"referrers"`
+		Paths     []*PathStats     `json:"paths"`
+	}
+
+	ReferrerStats struct {
+		Referrer string `json:"referrer"`
+		Count    int    `json:"count"`
+		Uniques  int    `json:"uniques"`
+	}
+
+	PathStats struct {
+		Repo    string `json:"repo"`
+		Path    string `json:"path"`
+		Title   string `json:"title"`
+		Count   int    `json:"count"`
+		Uniques int    `json:"uniques"`
+	}
+)
+
+// repoTraffic is everything fetched for one repository, the parts that
+// failed are empty.
+type repoTraffic struct {
+	repo      string
+	views     []TrafficDay
+	clones    []TrafficDay
+	referrers []*ReferrerStats
+	paths     []*PathStats
+}
+
+func (s *Loader) traffic(ctx context.Context, repo string, report *errorReport) repoTraffic {
+	result := repoTraffic{repo: repo}
+	var err error
+	if result.views, err = s.views(ctx, repo); err != nil {
+		report.add(repo, MetricViews, err)
+	}
+	if result.clones, err = s.clones(ctx, repo); err != nil {
+		report.add(repo, MetricClones, err)
+	}
+	if result.referrers, err = s.referrers(ctx, repo); err != nil {
+		report.add(repo, MetricReferrers, err)
+	}
+	if result.paths, err = s.paths(ctx, repo); err != nil {
+		report.add(repo, MetricPaths, err)
+	}
+	return result
+}
+
+func (s *Loader) views(ctx context.Context, repo string) ([]TrafficDay, error) {
+	traffic, err := s.queries.RepoTraffic(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]TrafficDay, 0, len(traffic.Views))
+	for _, view := range traffic.Views {
+		days = append(days, TrafficDay{Date: view.Timestamp, Count: view.Count, Uniques: view.Uniques})
+	}
+	return days, nil
+}
+
+func (s *Loader) clones(ctx context.Context, repo string) ([]TrafficDay, error) {
+	traffic, err := s.queries.RepoClones(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	days := make([]TrafficDay, 0, len(traffic.Clones))
+	for _, clone := range traffic.Clones {
+		days = append(days, TrafficDay{Date: clone.Timestamp, Count: clone.Count, Uniques: clone.Uniques})
+	}
+	return days, nil
+}
+
+func (s *Loader) referrers(ctx context.Context, repo string) ([]*ReferrerStats, error) {
+	referrers, err := s.queries.RepoReferrers(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*ReferrerStats, 0, len(*referrers))
+	for _, referrer := range *referrers {
+		result = append(result, &ReferrerStats{Referrer: referrer.Referrer, Count: referrer.Count, Uniques: referrer.Uniques})
+	}
+	return result, nil
+}
+
+func (s *Loader) paths(ctx context.Context, repo string) ([]*PathStats, error) {
+	paths, err := s.queries.RepoPopularPaths(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]*PathStats, 0, len(*paths))
+	for _, path := range *paths {
+		result = append(result, &PathStats{Repo: repo, Path: path.Path, Title: path.Title, Count: path.Count, Uniques: path.Uniques})
+	}
+	return result, nil
+}
+
+// loadTraffic returns the stored traffic history, empty without a store. It
+// reports false when there is no store or it could not be read, so a broken
+// history is not overwritten.
+func (s *Loader) loadTraffic(report *errorReport) (map[string]*TrafficHistory, bool) {
+	if s.trafficStore == nil {
+		return make(map[string]*TrafficHistory), false
+	}
+	history, err := s.trafficStore.LoadTraffic()
+	if err != nil {
+		report.add("", MetricViews, fmt.Errorf("load traffic history: %w", err))
+		return make(map[string]*TrafficHistory), false
+	}
+	if history == nil {
+		history = make(map[string]*TrafficHistory)
+	}
+	return history, true
+}
+
+func (s *Loader) saveTraffic(history map[string]*TrafficHistory, report *errorReport) {
+	if err := s.trafficStore.SaveTraffic(history); err != nil {
+		report.add("", MetricViews, fmt.Errorf("save traffic history: %w", err))
+	}
+}
+
+// trafficCollector merges the traffic of every repository, it is only used
+// from the goroutine reading the traffic channel.
+type trafficCollector struct {
+	history map[string]*TrafficHistory
+	repos   map[string]*RepoTrafficStats
+}
+
+func newTrafficCollector(history map[string]*TrafficHistory) *trafficCollector {
+	return &trafficCollector{
+		history: history,
+		repos:   make(map[string]*RepoTrafficStats),
+	}
+}
+
+func (c *trafficCollector) add(traffic repoTraffic) {
+	known := c.history[traffic.repo]
+	if known == nil {
+		known = &TrafficHistory{}
+		c.history[traffic.repo] = known
+	}
+	known.Views = mergeTrafficDays(known.Views, traffic.views)
+	known.Clones = mergeTrafficDays(known.Clones, traffic.clones)
+
+	c.repos[traffic.repo] = &RepoTrafficStats{
+		Views:     sumTraffic(traffic.views, time.Time{}),
+		Clones:    sumTraffic(traffic.clones, time.Time{}),
+		Referrers: traffic.referrers,
+		Paths:     traffic.paths,
+	}
+}
+
+// finish writes the collected traffic to stats. Only listed repositories that
+// are not ignored count, the accumulated totals include their stored history
+// also when their traffic could not be fetched in this run.
+func (c *trafficCollector) finish(stats *Stats, now time.Time) {
+	stats.Views = &ViewStats{}
+	stats.Clones = &CloneStats{}
+	referrers := make(map[string]*ReferrerStats)
+	var paths []*PathStats
+	for repo, known := range c.history {
+		repoStats := stats.Repos[repo]
+		if repoStats == nil || repoStats.Ignored {
+			continue
+		}
+		if traffic, ok := c.repos[repo]; ok {
+			repoStats.Traffic = traffic
+			stats.Views.Count += traffic.Views.Count
+			stats.Views.Uniques += traffic.Views.Uniques
+			stats.Clones.Count += traffic.Clones.Count
+			stats.Clones.Uniques += traffic.Clones.Uniques
+			for _, referrer := range traffic.Referrers {
+				total, ok := referrers[referrer.Referrer]
+				if !ok {
+					total = &ReferrerStats{Referrer: referrer.Referrer}
+					referrers[referrer.Referrer] = total
+				}
+				total.Count += referrer.Count
+				total.Uniques += referrer.Uniques
+			}
+			paths = append(paths, traffic.Paths...)
+		}
+		summarizeTraffic(stats.Views, known.Views, now)
+		summarizeTraffic(stats.Clones, known.Clones, now)
+	}
+
+	stats.Referrers = slices.SortedFunc(maps.Values(referrers), func(a, b *ReferrerStats) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Referrer, b.Referrer))
+	})
+	stats.Paths = slices.SortedFunc(slices.Values(paths), func(a, b *PathStats) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Repo, b.Repo), cmp.Compare(a.Path, b.Path))
+	})
+}
+
+// mergeTrafficDays adds fresh to known, a day present in both is replaced by
+// the fresh value since GitHub keeps updating the current day.
+func mergeTrafficDays(known, fresh []TrafficDay) []TrafficDay {
+	byDate := make(map[time.Time]TrafficDay, len(known)+len(fresh))
+	for _, day := range known {
+		byDate[day.Date.UTC()] = day
+	}
+	for _, day := range fresh {
+		day.Date = day.Date.UTC()
+		byDate[day.Date] = day
+	}
+	merged := make([]TrafficDay, 0, len(byDate))
+	for _, day := range byDate {
+		merged = append(merged, day)
+	}
+	slices.SortFunc(merged, func(a, b TrafficDay) int {
+		return a.Date.Compare(b.Date)
+	})
+	return merged
+}
+
+// sumTraffic totals the days on or after since, a zero since sums all days.
+func sumTraffic(days []TrafficDay, since time.Time) TrafficTotal {
+	var total TrafficTotal
+	for _, day := range days {
+		if day.Date.Before(since) {
+			continue
+		}
+		total.Count += day.Count
+		total.Uniques += day.Uniques
+	}
+	return total
+}
+
+// summarizeTraffic adds the accumulated totals of one repository to stats.
+func summarizeTraffic(stats *ViewStats, days []TrafficDay, now time.Time) {
+	if len(days) == 0 {
+		return
+	}
+	today := now.UTC().Truncate(24 * time.Hour)
+	if first := days[0].Date; stats.Since.IsZero() || first.Before(stats.Since) {
+		stats.Since = first
+	}
+	stats.AllTime.add(sumTraffic(days, time.Time{}))
+	stats.Last30Days.add(sumTraffic(days, today.AddDate(0, 0, -29)))
+	stats.Last90Days.add(sumTraffic(days, today.AddDate(0, 0, -89)))
+}
+
+func (t *TrafficTotal) add(other TrafficTotal) {
+	t.Count += other.Count
+	t.Uniques += other.Uniques
+}
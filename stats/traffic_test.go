@@ -0,0 +1,51 @@
+package stats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrafficTotalsListedRepos(t *testing.T) {
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	day := func(daysAgo, count int) TrafficDay {
+		return TrafficDay{Date: time.Date(2026, 10, 18-daysAgo, 0, 0, 0, 0, time.UTC), Count: count, Uniques: 1}
+	}
+	history := map[string]*TrafficHistory{
+		"bob/fetched": {Views: []TrafficDay{day(100, 1000)}},
+		"bob/skipped": {Views: []TrafficDay{day(100, 200), day(10, 20)}},
+		"bob/ignored": {Views: []TrafficDay{day(10, 5000)}},
+		"bob/deleted": {Views: []TrafficDay{day(10, 7000)}},
+	}
+	collector := newTrafficCollector(history)
+	collector.add(repoTraffic{repo: "bob/fetched", views: []TrafficDay{day(1, 3)}, referrers: []*ReferrerStats{{Referrer: "google.com", Count: 2}}})
+	collector.add(repoTraffic{repo: "bob/ignored", views: []TrafficDay{day(1, 900)}, referrers: []*ReferrerStats{{Referrer: "internal.example", Count: 9}}})
+
+	stats := &Stats{Repos: map[string]*RepoStats{
+		"bob/fetched": {Name: "bob/fetched"},
+		"bob/skipped": {Name: "bob/skipped"},
+		"bob/ignored": {Name: "bob/ignored", Ignored: true},
+	}}
+	collector.finish(stats, now)
+
+	if stats.Views.Count != 3 {
+		t.Errorf("Count = %d, want the 3 views fetched in this run", stats.Views.Count)
+	}
+	if len(stats.Referrers) != 1 || stats.Referrers[0].Referrer != "google.com" {
+		t.Errorf("Referrers = %v, want only google.com", stats.Referrers)
+	}
+	if want := (TrafficTotal{Count: 1223, Uniques: 4}); stats.Views.AllTime != want {
+		t.Errorf("AllTime = %+v, want %+v", stats.Views.AllTime, want)
+	}
+	if want := (TrafficTotal{Count: 23, Uniques: 2}); stats.Views.Last30Days != want {
+		t.Errorf("Last30Days = %+v, want %+v", stats.Views.Last30Days, want)
+	}
+	if want := day(100, 0).Date; !stats.Views.Since.Equal(want) {
+		t.Errorf("Since = %s, want %s", stats.Views.Since, want)
+	}
+	if stats.Views.AllTime.Count < stats.Views.Count {
+		t.Errorf("AllTime %d is below the %d views of the past two weeks", stats.Views.AllTime.Count, stats.Views.Count)
+	}
+	if stats.Repos["bob/fetched"].Traffic == nil || stats.Repos["bob/skipped"].Traffic != nil || stats.Repos["bob/ignored"].Traffic != nil {
+		t.Error("only the fetched repository should get per-repository traffic")
+	}
+}
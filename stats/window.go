@@ -0,0 +1,99 @@
+package stats
+
+import (
+	"fmt"
+	"time"
+)
+
+// Window limits contributions and lines changed to [Since, Until). The zero
+// Window covers all time.
+type Window struct {
+	Since time.Time `json:"since"`
+	Until time.Time `json:"until"`
+	// Label names the window on the cards, such as "Q3 2026".
+	Label string `json:"label"`
+}
+
+// WindowPresets are the names accepted by ParseWindow.
+var WindowPresets = []string{
+	"last-30-days",
+	"last-90-days",
+	"last-365-days",
+	"this-quarter",
+	"last-quarter",
+	"this-year",
+	"last-year",
+}
+
+func WithWindow(window Window) Option {
+	return func(s *Loader) {
+		s.window = window
+	}
+}
+
+func (w Window) IsZero() bool {
+	return w.Since.IsZero() && w.Until.IsZero()
+}
+
+// Contains reports whether t falls inside the window.
+func (w Window) Contains(t time.Time) bool {
+	return !t.Before(w.Since) && (w.Until.IsZero() || t.Before(w.Until))
+}
+
+// NewWindow returns the window covering the days since to until, both
+// inclusive. Either may be zero for an open end.
+func NewWindow(since, until time.Time) Window {
+	window := Window{Since: since}
+	if !until.IsZero() {
+		window.Until = until.AddDate(0, 0, 1)
+	}
+	switch {
+	case since.IsZero() && until.IsZero():
+	case since.IsZero():
+		window.Label = "until " + until.Format(time.DateOnly)
+	case until.IsZero():
+		window.Label = "since " + since.Format(time.DateOnly)
+	default:
+		window.Label = since.Format(time.DateOnly) + " to " + until.Format(time.DateOnly)
+	}
+	return window
+}
+
+// ParseWindow resolves one of WindowPresets relative to now, in UTC. An empty
+// preset is the zero Window.
+func ParseWindow(preset string, now time.Time) (Window, error) {
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+	quarter := (int(now.Month()) - 1) / 3
+	quarterStart := time.Date(now.Year(), time.Month(quarter*3+1), 1, 0, 0, 0, 0, time.UTC)
+	lastDays := func(days int) Window {
+		return Window{Since: tomorrow.AddDate(0, 0, -days), Until: tomorrow, Label: fmt.Sprintf("last %d days", days)}
+	}
+	quarterLabel := func(start time.Time) string {
+		return fmt.Sprintf("Q%d %d", (int(start.Month())-1)/3+1, start.Year())
+	}
+	switch preset {
+	case "":
+		return Window{}, nil
+	case "last-30-days":
+		return lastDays(30), nil
+	case "last-90-days":
+		return lastDays(90), nil
+	case "last-365-days":
+		return lastDays(365), nil
+	case "this-quarter":
+		return Window{Since: quarterStart, Until: tomorrow, Label: quarterLabel(quarterStart)}, nil
+	case "last-quarter":
+		start := quarterStart.AddDate(0, -3, 0)
+		return Window{Since: start, Until: quarterStart, Label: quarterLabel(start)}, nil
+	case "this-year":
+		start := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC)
+		return Window{Since: start, Until: tomorrow, Label: fmt.Sprint(now.Year())}, nil
+	case "last-year":
+		start := time.Date(now.Year()-1, 1, 1, 0, 0, 0, 0, time.UTC)
+		return Window{Since: start, Until: start.AddDate(1, 0, 0), Label: fmt.Sprint(now.Year() - 1)}, nil
+	default:
+		return Window{}, fmt.Errorf("unknown window %q", preset)
+	}
+}
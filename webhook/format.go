@@ -0,0 +1,224 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"strings"
+	"text/template"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+const topLanguagesCount = 5
+
+type Formatter interface {
+	Format(payload *Payload) ([]byte, error)
+}
+
+type FormatterFunc func(payload *Payload) ([]byte, error)
+
+func (f FormatterFunc) Format(payload *Payload) ([]byte, error) {
+	return f(payload)
+}
+
+var (
+	JSONFormatter    Formatter = FormatterFunc(formatJSON)
+	SlackFormatter   Formatter = FormatterFunc(formatSlack)
+	DiscordFormatter Formatter = FormatterFunc(formatDiscord)
+	TeamsFormatter   Formatter = FormatterFunc(formatTeams)
+)
+
+// NewFormatter resolves a format name from the config, templatePath is only
+// used by the "template" format.
+func NewFormatter(format, templatePath string) (Formatter, error) {
+	switch strings.ToLower(format) {
+	case "", "json":
+		return JSONFormatter, nil
+	case "slack":
+		return SlackFormatter, nil
+	case "discord":
+		return DiscordFormatter, nil
+	case "teams":
+		return TeamsFormatter, nil
+	case "template":
+		return NewTemplateFormatter(templatePath)
+	default:
+		return nil, fmt.Errorf("unknown webhook format %q", format)
+	}
+}
+
+// Summary is the condensed view of the stats shared by the chat formatters and
+// available to user templates as .Summary.
+type Summary struct {
+	Title         string
+	Stars         int
+	Forks         int
+	Contributions int
+	// ContributionsLabel names the window the contributions are limited to,
+	// like the overview card.
+	ContributionsLabel string
+	TopLanguages       []*stats.LanguageStats
+}
+
+func NewSummary(data *stats.Stats) Summary {
+	summary := Summary{
+		Title:              fmt.Sprintf("%s's GitHub Statistics", data.Name),
+		Stars:              data.Stargazers,
+		Forks:              data.Forks,
+		ContributionsLabel: "All-time contributions",
+	}
+	if data.Window != nil {
+		summary.ContributionsLabel = fmt.Sprintf("Contributions (%s)", data.Window.Label)
+	}
+	if data.Contributions != nil {
+		summary.Contributions = data.Contributions.TotalContributions
+	}
+	summary.TopLanguages = slices.SortedFunc(maps.Values(data.Languages), func(s1 *stats.LanguageStats, s2 *stats.LanguageStats) int {
+		return s2.Size - s1.Size
+	})
+	if len(summary.TopLanguages) > topLanguagesCount {
+		summary.TopLanguages = summary.TopLanguages[:topLanguagesCount]
+	}
+	return summary
+}
+
+func (s Summary) facts() [][2]string {
+	return [][2]string{
+		{"Stars", fmt.Sprintf("%d", s.Stars)},
+		{"Forks", fmt.Sprintf("%d", s.Forks)},
+		{s.ContributionsLabel, fmt.Sprintf("%d", s.Contributions)},
+	}
+}
+
+func (s Summary) languages() string {
+	if len(s.TopLanguages) == 0 {
+		return "-"
+	}
+	items := make([]string, 0, len(s.TopLanguages))
+	for _, lang := range s.TopLanguages {
+		items = append(items, fmt.Sprintf("%s %.1f%%", lang.Name, lang.Proportion))
+	}
+	return strings.Join(items, ", ")
+}
+
+func formatJSON(payload *Payload) ([]byte, error) {
+	return json.MarshalIndent(payload, "", "  ")
+}
+
+func formatSlack(payload *Payload) ([]byte, error) {
+	summary := NewSummary(payload.Status)
+	fields := make([]map[string]any, 0, 3)
+	for _, fact := range summary.facts() {
+		fields = append(fields, map[string]any{
+			"type": "mrkdwn",
+			"text": fmt.Sprintf("*%s*\n%s", fact[0], fact[1]),
+		})
+	}
+	return json.Marshal(map[string]any{
+		"text": summary.Title,
+		"blocks": []map[string]any{
+			{
+				"type": "header",
+				"text": map[string]any{"type": "plain_text", "text": summary.Title},
+			},
+			{
+				"type":   "section",
+				"fields": fields,
+			},
+			{
+				"type": "section",
+				"text": map[string]any{"type": "mrkdwn", "text": "*Top languages*\n" + summary.languages()},
+			},
+		},
+	})
+}
+
+func formatDiscord(payload *Payload) ([]byte, error) {
+	summary := NewSummary(payload.Status)
+	fields := make([]map[string]any, 0, 4)
+	for _, fact := range summary.facts() {
+		fields = append(fields, map[string]any{"name": fact[0], "value": fact[1], "inline": true})
+	}
+	fields = append(fields, map[string]any{"name": "Top languages", "value": summary.languages()})
+	embed := map[string]any{
+		"title":  summary.Title,
+		"fields": fields,
+	}
+	if len(summary.TopLanguages) > 0 {
+		var color int
+		if _, err := fmt.Sscanf(summary.TopLanguages[0].Color, "#%06x", &color); err == nil {
+			embed["color"] = color
+		}
+	}
+	return json.Marshal(map[string]any{
+		"embeds": []map[string]any{embed},
+	})
+}
+
+func formatTeams(payload *Payload) ([]byte, error) {
+	summary := NewSummary(payload.Status)
+	facts := make([]map[string]any, 0, 4)
+	for _, fact := range summary.facts() {
+		facts = append(facts, map[string]any{"title": fact[0], "value": fact[1]})
+	}
+	facts = append(facts, map[string]any{"title": "Top languages", "value": summary.languages()})
+	return json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{
+			{
+				"contentType": "application/vnd.microsoft.card.adaptive",
+				"content": map[string]any{
+					"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+					"type":    "AdaptiveCard",
+					"version": "1.4",
+					"body": []map[string]any{
+						{"type": "TextBlock", "size": "Large", "weight": "Bolder", "text": summary.Title},
+						{"type": "FactSet", "facts": facts},
+					},
+				},
+			},
+		},
+	})
+}
+
+type templateFormatter struct {
+	tmpl *template.Template
+}
+
+// NewTemplateFormatter loads a text/template that is executed with the
+// payload fields plus .Summary, the output is sent as the request body.
+func NewTemplateFormatter(path string) (Formatter, error) {
+	if path == "" {
+		return nil, fmt.Errorf("template format requires a template path")
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(string(text))
+	if err != nil {
+		return nil, err
+	}
+	return &templateFormatter{tmpl: tmpl}, nil
+}
+
+func (f *templateFormatter) Format(payload *Payload) ([]byte, error) {
+	var buf bytes.Buffer
+	err := f.tmpl.Execute(&buf, struct {
+		*Payload
+		Summary Summary
+	}{payload, NewSummary(payload.Status)})
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
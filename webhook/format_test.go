@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+func TestSummaryContributionsLabel(t *testing.T) {
+	data := &stats.Stats{Name: "bob", Contributions: &stats.ContributionsStats{TotalContributions: 42}}
+	if got := NewSummary(data).facts()[2]; got != [2]string{"All-time contributions", "42"} {
+		t.Errorf("facts without window = %v", got)
+	}
+	data.Window = &stats.Window{Label: "Q3 2026"}
+	if got := NewSummary(data).facts()[2]; got != [2]string{"Contributions (Q3 2026)", "42"} {
+		t.Errorf("facts with window = %v", got)
+	}
+}
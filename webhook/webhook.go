@@ -0,0 +1,173 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+// SchemaVersion is bumped whenever the payload changes in a way receivers
+// have to handle.
+const SchemaVersion = 1
+
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+
+	SignatureHeader = "X-Hub-Signature-256"
+)
+
+// retryBackoff is the wait before the first retry, it doubles after every
+// attempt.
+var retryBackoff = time.Second
+
+type Payload struct {
+	SchemaVersion int            `json:"schema_version"`
+	Status        *stats.Stats   `json:"status"`
+	Config        map[string]any `json:"config"`
+}
+
+func NewPayload(status *stats.Stats, config map[string]any) *Payload {
+	return &Payload{
+		SchemaVersion: SchemaVersion,
+		Status:        status,
+		Config:        config,
+	}
+}
+
+// Target is one webhook receiver. A zero Timeout or MaxRetries falls back to
+// the defaults, a negative MaxRetries disables retries, a nil Formatter
+// sends the raw JSON payload and an empty ContentType is application/json.
+type Target struct {
+	URL         string
+	Secret      string
+	Timeout     time.Duration
+	MaxRetries  int
+	Formatter   Formatter
+	ContentType string
+}
+
+// String identifies the target by host only, webhook URLs often embed tokens.
+func (t Target) String() string {
+	u, err := url.Parse(t.URL)
+	if err != nil || u.Host == "" {
+		return "invalid url"
+	}
+	return u.Scheme + "://" + u.Host
+}
+
+type Result struct {
+	Target   string
+	Attempts int
+	Err      error
+}
+
+// Sign returns the X-Hub-Signature-256 value for body, the hex encoded
+// HMAC-SHA256 prefixed with "sha256=".
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Send delivers the payload to every target concurrently and returns one
+// result per target in the same order.
+func Send(ctx context.Context, targets []Target, payload *Payload) []Result {
+	results := make([]Result, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			results[i] = target.deliver(ctx, payload)
+		}(i, target)
+	}
+	wg.Wait()
+	return results
+}
+
+func (t Target) deliver(ctx context.Context, payload *Payload) Result {
+	result := Result{Target: t.String()}
+	formatter := t.Formatter
+	if formatter == nil {
+		formatter = JSONFormatter
+	}
+	body, err := formatter.Format(payload)
+	if err != nil {
+		result.Err = fmt.Errorf("format payload: %w", err)
+		return result
+	}
+	timeout := t.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	maxRetries := t.MaxRetries
+	switch {
+	case maxRetries == 0:
+		maxRetries = DefaultMaxRetries
+	case maxRetries < 0:
+		maxRetries = 0
+	}
+	client := &http.Client{Timeout: timeout}
+	backoff := retryBackoff
+	for {
+		result.Attempts++
+		retry, err := t.post(ctx, client, body)
+		result.Err = err
+		if err == nil || !retry || result.Attempts > maxRetries {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			result.Err = ctx.Err()
+			return result
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// post sends one attempt and reports whether a failure is worth retrying.
+func (t Target) post(ctx context.Context, client *http.Client, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "POST", t.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	contentType := t.ContentType
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	req.Header.Set("Content-Type", contentType)
+	if t.Secret != "" {
+		req.Header.Set(SignatureHeader, Sign(t.Secret, body))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			// Drop the URL from the message, it may carry a token.
+			err = urlErr.Err
+		}
+		return true, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusRequestTimeout
+	return retry, fmt.Errorf("unexpected status %s", resp.Status)
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/TBXark/github-status/stats"
+)
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"schema_version":1}`)
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if got, want := Sign("s3cret", body), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+
+	var header string
+	var received []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header = r.Header.Get(SignatureHeader)
+		received, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+	results := Send(context.Background(), []Target{{URL: server.URL, Secret: "s3cret"}}, NewPayload(&stats.Stats{Name: "bob"}, nil))
+	if results[0].Err != nil {
+		t.Fatal(results[0].Err)
+	}
+	if header != Sign("s3cret", received) {
+		t.Errorf("%s = %q, want the signature of the body", SignatureHeader, header)
+	}
+}
+
+// statusServer answers with the statuses in order and then with 200, it
+// counts the requests.
+func statusServer(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			w.WriteHeader(statuses[n-1])
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestSendRetries(t *testing.T) {
+	backoff := retryBackoff
+	retryBackoff = 10 * time.Millisecond
+	t.Cleanup(func() { retryBackoff = backoff })
+
+	tests := []struct {
+		name       string
+		statuses   []int
+		maxRetries int
+		attempts   int
+		wantErr    bool
+	}{
+		{"retry 5xx and 429", []int{http.StatusBadGateway, http.StatusTooManyRequests}, 0, 3, false},
+		{"give up on 4xx", []int{http.StatusNotFound}, 0, 1, true},
+		{"give up after MaxRetries", []int{500, 500, 500}, 2, 3, true},
+		{"negative MaxRetries disables retries", []int{503}, -1, 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := statusServer(t, tt.statuses...)
+			start := time.Now()
+			result := Send(context.Background(), []Target{{URL: server.URL, MaxRetries: tt.maxRetries}}, NewPayload(&stats.Stats{}, nil))[0]
+			if result.Attempts != tt.attempts || int(requests.Load()) != tt.attempts {
+				t.Errorf("Attempts = %d, requests = %d, want %d", result.Attempts, requests.Load(), tt.attempts)
+			}
+			if (result.Err != nil) != tt.wantErr {
+				t.Errorf("Err = %v, want error %v", result.Err, tt.wantErr)
+			}
+			// The waits double: 10ms, 20ms, ...
+			if minWait := retryBackoff * time.Duration(1<<(tt.attempts-1)-1); time.Since(start) < minWait {
+				t.Errorf("retried after %s, want a backoff of at least %s", time.Since(start), minWait)
+			}
+		})
+	}
+}
+
+func TestSendResultsPerTarget(t *testing.T) {
+	ok, _ := statusServer(t)
+	failing, _ := statusServer(t, http.StatusForbidden)
+	targets := []Target{
+		{URL: failing.URL + "/hook?token=abc"},
+		{URL: ok.URL},
+		{URL: "://invalid"},
+		{URL: ok.URL, Formatter: FormatterFunc(func(*Payload) ([]byte, error) { return nil, io.ErrUnexpectedEOF })},
+	}
+	results := Send(context.Background(), targets, NewPayload(&stats.Stats{}, nil))
+	if len(results) != len(targets) {
+		t.Fatalf("%d results for %d targets", len(results), len(targets))
+	}
+	if results[0].Target != failing.URL || results[0].Err == nil {
+		t.Errorf("results[0] = %+v, want an error for %s without the query", results[0], failing.URL)
+	}
+	if results[1].Target != ok.URL || results[1].Err != nil || results[1].Attempts != 1 {
+		t.Errorf("results[1] = %+v, want a delivery to %s", results[1], ok.URL)
+	}
+	if results[2].Target != "invalid url" || results[2].Err == nil {
+		t.Errorf("results[2] = %+v, want an invalid url error", results[2])
+	}
+	if results[3].Err == nil || results[3].Attempts != 0 {
+		t.Errorf("results[3] = %+v, want a format error before any attempt", results[3])
+	}
+}